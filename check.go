@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/token"
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/go-openapi/loads"
+	"github.com/go-openapi/spec"
+	"github.com/xucx/go2swag/generator"
+)
+
+// check rebuilds the spec and compares it with the file at -o without
+// touching it, so hooks can enforce that the committed spec is fresh.
+func check(opt options, diags *generator.Diagnostics) int {
+	swag := generate(opt, diags)
+	if swag == nil {
+		return 1
+	}
+
+	switch filepath.Ext(opt.Out) {
+	case ".go", ".md", ".html", ".htm":
+		return checkSource(opt, swag, diags)
+	}
+
+	current, err := loads.Spec(opt.Out)
+	if err != nil {
+		diags.Errorf(token.Position{Filename: opt.Out}, generator.CodeInput, "%v", err)
+		return 1
+	}
+
+	changes, err := generator.Compare(current.Spec(), swag)
+	if err != nil {
+		diags.Errorf(token.Position{Filename: opt.Out}, generator.CodeInput, "%v", err)
+		return 1
+	}
+	if len(changes) == 0 {
+		return 0
+	}
+
+	fmt.Printf("%s is out of date, %d change(s):\n", opt.Out, len(changes))
+	for _, c := range changes {
+		fmt.Println("  " + c.String())
+	}
+	return 1
+}
+
+// checkSource compares a generated Go file or reference document byte for
+// byte, loads cannot read the spec back from them.
+func checkSource(opt options, swag *spec.Swagger, diags *generator.Diagnostics) int {
+	want, err := generator.Encode(swag, true, opt.Out)
+	if err != nil {
+		diags.Errorf(token.Position{Filename: opt.Out}, generator.CodeOutput, "%v", err)
+		return 1
+	}
+	have, err := ioutil.ReadFile(opt.Out)
+	if err != nil {
+		diags.Errorf(token.Position{Filename: opt.Out}, generator.CodeInput, "%v", err)
+		return 1
+	}
+	if bytes.Equal(have, want) {
+		return 0
+	}
+	fmt.Printf("%s is out of date\n", opt.Out)
+	return 1
+}
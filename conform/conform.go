@@ -0,0 +1,149 @@
+// Package conform is a net/http middleware checking the responses of a
+// service against its generated spec while it runs. It is meant for
+// development builds: responses are copied to be checked, and mismatches
+// are logged or turned into errors so that drift between handlers and
+// their annotations shows up before clients notice.
+package conform
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/go-openapi/loads"
+	"github.com/go-openapi/spec"
+	"github.com/xucx/go2swag/generator"
+)
+
+// Options tune the middleware.
+type Options struct {
+	// Report receives every mismatch, which is logged by default.
+	Report func(Mismatch)
+	// Fail answers mismatching responses with a 500 describing the
+	// mismatch instead, which holds back the response until the handler
+	// returns.
+	Fail bool
+	// Undeclared reports requests no operation of the spec matches too.
+	Undeclared bool
+}
+
+// Mismatch is a response that does not conform to the spec.
+type Mismatch struct {
+	// Operation is the operation id, or its method and path without one,
+	// and empty when no operation matches the request.
+	Operation string   `json:"operation,omitempty"`
+	Method    string   `json:"method"`
+	Path      string   `json:"path"`
+	Status    int      `json:"status"`
+	Errors    []string `json:"errors"`
+}
+
+func (self Mismatch) Error() string {
+	op := ""
+	if self.Operation != "" && self.Operation != self.Method+" "+self.Path {
+		op = " (" + self.Operation + ")"
+	}
+	return fmt.Sprintf("%s %s%s answered %d: %s", self.Method, self.Path, op, self.Status, strings.Join(self.Errors, "; "))
+}
+
+// Load reads a spec file, JSON or YAML, like go2swag writes it.
+func Load(path string) (*spec.Swagger, error) {
+	doc, err := loads.Spec(path)
+	if err != nil {
+		return nil, err
+	}
+	return doc.Spec(), nil
+}
+
+// Middleware checks the responses of the handler it wraps against the
+// operations of sw, matched by method and path template, either {name} or
+// :name parameters.
+func Middleware(sw *spec.Swagger, opt Options) func(http.Handler) http.Handler {
+	checker := generator.NewResponseChecker(sw)
+	report := opt.Report
+	if report == nil {
+		report = func(m Mismatch) { log.Printf("conform: %v", m) }
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rec := &recorder{w: w, buffer: opt.Fail, status: http.StatusOK}
+			next.ServeHTTP(rec, r)
+
+			m := Mismatch{Method: r.Method, Path: r.URL.Path, Status: rec.status}
+			m.Operation, m.Errors = checker.Check(r, rec.status, rec.body.Bytes())
+			if m.Operation == "" && opt.Undeclared {
+				m.Errors = []string{"no operation of the spec matches the request"}
+			}
+			if len(m.Errors) > 0 {
+				report(m)
+			}
+			if !opt.Fail {
+				return
+			}
+
+			if len(m.Errors) == 0 {
+				rec.flush()
+				return
+			}
+			for name := range w.Header() {
+				w.Header().Del(name)
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusInternalServerError)
+			enc := json.NewEncoder(w)
+			enc.SetIndent("", "  ")
+			enc.Encode(m)
+		})
+	}
+}
+
+// recorder copies the response for checking, passing it on as it is
+// written, or holding it back when buffer is set.
+type recorder struct {
+	w           http.ResponseWriter
+	buffer      bool
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (self *recorder) Header() http.Header {
+	return self.w.Header()
+}
+
+func (self *recorder) WriteHeader(status int) {
+	if self.wroteHeader {
+		return
+	}
+	self.status, self.wroteHeader = status, true
+	if !self.buffer {
+		self.w.WriteHeader(status)
+	}
+}
+
+func (self *recorder) Write(b []byte) (int, error) {
+	if !self.wroteHeader {
+		self.WriteHeader(http.StatusOK)
+	}
+	self.body.Write(b)
+	if self.buffer {
+		return len(b), nil
+	}
+	return self.w.Write(b)
+}
+
+func (self *recorder) Flush() {
+	if f, ok := self.w.(http.Flusher); ok && !self.buffer {
+		f.Flush()
+	}
+}
+
+// flush writes the held back response.
+func (self *recorder) flush() {
+	self.w.WriteHeader(self.status)
+	self.w.Write(self.body.Bytes())
+}
@@ -0,0 +1,182 @@
+package conform
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/go-openapi/spec"
+)
+
+const testSpec = `{
+  "swagger": "2.0",
+  "info": {"title": "conform", "version": "1"},
+  "paths": {
+    "/items/{id}": {
+      "get": {
+        "operationId": "getItem",
+        "parameters": [{"name": "id", "in": "path", "type": "integer", "required": true}],
+        "responses": {"200": {"description": "ok", "schema": {"$ref": "#/definitions/Item"}}}
+      }
+    }
+  },
+  "definitions": {
+    "Item": {"type": "object", "required": ["id"], "properties": {"id": {"type": "integer"}, "name": {"type": "string"}}}
+  }
+}`
+
+// testHandler answers item 1 as declared and item 2 with a name that is no
+// string.
+var testHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("X-Item", "yes")
+	switch r.URL.Path {
+	case "/items/1":
+		fmt.Fprint(w, `{"id": 1, "name": "one"}`)
+	case "/items/2":
+		fmt.Fprint(w, `{"id": 2, "name": 2}`)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+})
+
+func testServe(t *testing.T, opt Options, target string) (*httptest.ResponseRecorder, []Mismatch) {
+	t.Helper()
+	sw := new(spec.Swagger)
+	if err := json.Unmarshal([]byte(testSpec), sw); err != nil {
+		t.Fatal(err)
+	}
+	reported := []Mismatch{}
+	opt.Report = func(m Mismatch) { reported = append(reported, m) }
+	w := httptest.NewRecorder()
+	Middleware(sw, opt)(testHandler).ServeHTTP(w, httptest.NewRequest("GET", target, nil))
+	return w, reported
+}
+
+var wrongName = Mismatch{Operation: "getItem", Method: "GET", Path: "/items/2", Status: 200, Errors: []string{"body.name must be a string"}}
+
+func TestMiddleware(t *testing.T) {
+	cases := []struct {
+		name, target string
+		opt          Options
+		status       int
+		body         string
+		header       string
+		reported     []Mismatch
+	}{
+		{
+			name: "conforming", target: "/items/1",
+			status: 200, body: `{"id": 1, "name": "one"}`, header: "yes", reported: []Mismatch{},
+		},
+		{
+			name: "mismatch is reported and passed on", target: "/items/2",
+			status: 200, body: `{"id": 2, "name": 2}`, header: "yes", reported: []Mismatch{wrongName},
+		},
+		{
+			name: "undeclared request", target: "/other",
+			status: 404, header: "yes", reported: []Mismatch{},
+		},
+		{
+			name: "undeclared request reported", target: "/other", opt: Options{Undeclared: true},
+			status: 404, header: "yes", reported: []Mismatch{{Method: "GET", Path: "/other", Status: 404, Errors: []string{"no operation of the spec matches the request"}}},
+		},
+		{
+			name: "conforming with fail", target: "/items/1", opt: Options{Fail: true},
+			status: 200, body: `{"id": 1, "name": "one"}`, header: "yes", reported: []Mismatch{},
+		},
+		{
+			name: "mismatch with fail", target: "/items/2", opt: Options{Fail: true},
+			status: 500, reported: []Mismatch{wrongName},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			w, reported := testServe(t, c.opt, c.target)
+			if w.Code != c.status {
+				t.Errorf("got status %d, want %d", w.Code, c.status)
+			}
+			if got := w.Header().Get("X-Item"); got != c.header {
+				t.Errorf("got header X-Item %q, want %q", got, c.header)
+			}
+			if !reflect.DeepEqual(reported, c.reported) {
+				t.Errorf("got reported %+v, want %+v", reported, c.reported)
+			}
+			if c.status == http.StatusInternalServerError {
+				var got Mismatch
+				if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+					t.Fatalf("%v: %s", err, w.Body)
+				}
+				if !reflect.DeepEqual(got, wrongName) || w.Header().Get("Content-Type") != "application/json" {
+					t.Errorf("got %s answering %+v, want the mismatch", w.Header().Get("Content-Type"), got)
+				}
+			} else if got := w.Body.String(); got != c.body {
+				t.Errorf("got body %q, want %q", got, c.body)
+			}
+		})
+	}
+}
+
+func TestReportLog(t *testing.T) {
+	out := new(bytes.Buffer)
+	log.SetOutput(out)
+	log.SetFlags(0)
+	defer func() {
+		log.SetOutput(os.Stderr)
+		log.SetFlags(log.LstdFlags)
+	}()
+
+	sw := new(spec.Swagger)
+	if err := json.Unmarshal([]byte(testSpec), sw); err != nil {
+		t.Fatal(err)
+	}
+	handler := Middleware(sw, Options{})(testHandler)
+	for _, target := range []string{"/items/1", "/items/2"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", target, nil))
+	}
+	want := "conform: GET /items/2 (getItem) answered 200: body.name must be a string\n"
+	if out.String() != want {
+		t.Errorf("got log %q, want %q", out, want)
+	}
+}
+
+func TestRecorder(t *testing.T) {
+	for _, buffer := range []bool{false, true} {
+		w := httptest.NewRecorder()
+		rec := &recorder{w: w, buffer: buffer, status: http.StatusOK}
+		rec.WriteHeader(http.StatusCreated)
+		rec.WriteHeader(http.StatusTeapot)
+		rec.Write([]byte("a"))
+		rec.Flush()
+		rec.Write([]byte("b"))
+
+		if rec.status != http.StatusCreated || rec.body.String() != "ab" {
+			t.Errorf("buffer %v: recorded %d %q, want 201 \"ab\"", buffer, rec.status, rec.body.String())
+		}
+		passed := w.Body.String()
+		if buffer {
+			if passed != "" || w.Flushed {
+				t.Errorf("buffer %v: passed on %q before the handler returned", buffer, passed)
+			}
+			rec.flush()
+		} else if !w.Flushed {
+			t.Errorf("buffer %v: flush was not passed on", buffer)
+		}
+		if w.Code != http.StatusCreated || w.Body.String() != "ab" {
+			t.Errorf("buffer %v: passed on %d %q, want 201 \"ab\"", buffer, w.Code, w.Body.String())
+		}
+	}
+
+	// writing without a status answers 200
+	w := httptest.NewRecorder()
+	rec := &recorder{w: w, status: http.StatusOK}
+	rec.Write([]byte("{}"))
+	if !rec.wroteHeader || w.Code != http.StatusOK || !strings.HasPrefix(w.Body.String(), "{") {
+		t.Errorf("got %d %q, want 200 {}", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,542 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/token"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/go-openapi/loads"
+	"github.com/go-openapi/spec"
+	"github.com/xucx/go2swag/generator"
+)
+
+type diffOptions struct {
+	Old    string `goptions:"--old, description='old spec file, or git revision with --git'"`
+	New    string `goptions:"--new, description='new spec file, or git revision with --git (default: working tree)'"`
+	Git    bool   `goptions:"--git, description='scan the sources at git revisions instead of reading spec files'"`
+	Format string `goptions:"--format, description='text or json'"`
+}
+
+type apiChange struct {
+	Breaking  bool   `json:"breaking"`
+	Operation string `json:"operation"`
+	Location  string `json:"location,omitempty"`
+	Message   string `json:"message"`
+}
+
+// diff compares two specs and exits non-zero when a change would break
+// existing clients, so it can gate releases.
+func diff(opt options, diags *generator.Diagnostics) int {
+	prev, next := diffSpecs(opt, diags)
+	if prev == nil || next == nil {
+		return 1
+	}
+
+	changes := compareAPIs(prev, next)
+	if err := renderAPIChanges(os.Stdout, opt.Diff.Format, changes); err != nil {
+		diags.Errorf(token.Position{}, generator.CodeOutput, "%v", err)
+		return 1
+	}
+
+	for _, c := range changes {
+		if c.Breaking {
+			return 1
+		}
+	}
+	return 0
+}
+
+func diffSpecs(opt options, diags *generator.Diagnostics) (prev, next *spec.Swagger) {
+	if opt.Diff.Git {
+		if opt.Diff.Old == "" {
+			diags.Errorf(token.Position{}, generator.CodeInput, "diff --git needs --old <revision>")
+			return nil, nil
+		}
+		prev = specAtRevision(opt, opt.Diff.Old, diags)
+		if opt.Diff.New == "" {
+			next = generateIn(".", opt, diags)
+		} else {
+			next = specAtRevision(opt, opt.Diff.New, diags)
+		}
+		return prev, next
+	}
+
+	if opt.Diff.Old == "" || opt.Diff.New == "" {
+		diags.Errorf(token.Position{}, generator.CodeInput, "diff needs --old and --new spec files")
+		return nil, nil
+	}
+	for i, path := range []string{opt.Diff.Old, opt.Diff.New} {
+		doc, err := loads.Spec(path)
+		if err != nil {
+			diags.Errorf(token.Position{Filename: path}, generator.CodeInput, "%v", err)
+			continue
+		}
+		if i == 0 {
+			prev = doc.Spec()
+		} else {
+			next = doc.Spec()
+		}
+	}
+	return prev, next
+}
+
+// specAtRevision checks rev out into a temporary worktree and generates the
+// spec there, from the same directory relative to the repository root.
+func specAtRevision(opt options, rev string, diags *generator.Diagnostics) *spec.Swagger {
+	out, err := exec.Command("git", "rev-parse", "--show-toplevel").Output()
+	if err != nil {
+		diags.Errorf(token.Position{}, generator.CodeInput, "git rev-parse: %v", err)
+		return nil
+	}
+	top := strings.TrimSpace(string(out))
+	cwd, err := os.Getwd()
+	if err != nil {
+		diags.Errorf(token.Position{}, generator.CodeInput, "%v", err)
+		return nil
+	}
+	rel, err := filepath.Rel(top, cwd)
+	if err != nil {
+		diags.Errorf(token.Position{}, generator.CodeInput, "%v", err)
+		return nil
+	}
+
+	tmp, err := ioutil.TempDir("", "go2swag-")
+	if err != nil {
+		diags.Errorf(token.Position{}, generator.CodeInput, "%v", err)
+		return nil
+	}
+	defer os.RemoveAll(tmp)
+
+	wt := filepath.Join(tmp, "tree")
+	if out, err := exec.Command("git", "worktree", "add", "--detach", wt, rev).CombinedOutput(); err != nil {
+		diags.Errorf(token.Position{}, generator.CodeInput, "git worktree add %s: %v: %s", rev, err, strings.TrimSpace(string(out)))
+		return nil
+	}
+	defer exec.Command("git", "worktree", "remove", "--force", wt).Run()
+
+	inputs := []string{}
+	for _, in := range opt.In {
+		if !filepath.IsAbs(in) {
+			in = filepath.Join(wt, rel, in)
+		}
+		inputs = append(inputs, in)
+	}
+	opt.In = inputs
+	// paths in a throwaway worktree would only fill the cache with misses
+	opt.NoCache = true
+	return generateIn(filepath.Join(wt, rel), opt, diags)
+}
+
+type direction int
+
+const (
+	dirRequest direction = iota
+	dirResponse
+)
+
+type apiDiffer struct {
+	prev, next *spec.Swagger
+	changes    []apiChange
+	operation  string
+}
+
+func (self *apiDiffer) report(breaking bool, location, format string, args ...interface{}) {
+	self.changes = append(self.changes, apiChange{
+		Breaking:  breaking,
+		Operation: self.operation,
+		Location:  location,
+		Message:   fmt.Sprintf(format, args...),
+	})
+}
+
+func compareAPIs(prev, next *spec.Swagger) []apiChange {
+	d := apiDiffer{prev: prev, next: next}
+
+	prevOps, nextOps := operationsOf(prev), operationsOf(next)
+	nextByID := map[string]string{}
+	for key, o := range nextOps {
+		if o.op.ID != "" {
+			nextByID[o.op.ID] = key
+		}
+	}
+	prevIDs := map[string]bool{}
+	for _, o := range prevOps {
+		prevIDs[o.op.ID] = true
+	}
+
+	for _, key := range sortedOpKeys(prevOps) {
+		o := prevOps[key]
+		d.operation = o.name
+		other, ok := nextOps[key]
+		if !ok {
+			if moved, ok := nextByID[o.op.ID]; ok && o.op.ID != "" {
+				d.report(true, "", "operation %s moved to %s", o.op.ID, nextOps[moved].name)
+				d.operation = nextOps[moved].name
+				d.compareOperation(o, nextOps[moved])
+				continue
+			}
+			d.report(true, "", "operation removed")
+			continue
+		}
+		d.operation = other.name
+		d.compareOperation(o, other)
+	}
+
+	for _, key := range sortedOpKeys(nextOps) {
+		if _, ok := prevOps[key]; ok {
+			continue
+		}
+		if o := nextOps[key]; o.op.ID != "" && prevIDs[o.op.ID] {
+			continue
+		}
+		d.operation = nextOps[key].name
+		d.report(false, "", "operation added")
+	}
+
+	return d.changes
+}
+
+// apiOperation is an operation of a spec and where it is.
+type apiOperation struct {
+	// name is the method and path, e.g. GET /users/{id}.
+	name, path string
+	op         *spec.Operation
+}
+
+// operationsOf are the operations of sw keyed by method and path with
+// anonymous parameters, so that renaming a path parameter keeps the key.
+func operationsOf(sw *spec.Swagger) map[string]apiOperation {
+	ops := map[string]apiOperation{}
+	if sw.Paths == nil {
+		return ops
+	}
+	for path, item := range sw.Paths.Paths {
+		for method, op := range map[string]*spec.Operation{
+			"GET": item.Get, "PUT": item.Put, "POST": item.Post, "DELETE": item.Delete,
+			"OPTIONS": item.Options, "HEAD": item.Head, "PATCH": item.Patch,
+		} {
+			if op != nil {
+				ops[method+" "+generator.NormalizePath(path)] = apiOperation{name: method + " " + path, path: path, op: op}
+			}
+		}
+	}
+	return ops
+}
+
+func sortedOpKeys(ops map[string]apiOperation) []string {
+	keys := []string{}
+	for k := range ops {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (self *apiDiffer) compareOperation(prevOp, nextOp apiOperation) {
+	prev, next := prevOp.op, nextOp.op
+
+	// path parameters are matched by position, their names are not sent
+	renamed := map[string]string{}
+	if generator.NormalizePath(prevOp.path) == generator.NormalizePath(nextOp.path) {
+		nextNames := generator.PathParams(nextOp.path)
+		for i, name := range generator.PathParams(prevOp.path) {
+			if name != nextNames[i] {
+				renamed[name] = nextNames[i]
+			}
+		}
+	}
+
+	params := func(op *spec.Operation, renamed map[string]string) (map[string]spec.Parameter, []string) {
+		m := map[string]spec.Parameter{}
+		keys := []string{}
+		for _, p := range op.Parameters {
+			name := p.Name
+			if to, ok := renamed[name]; ok && p.In == "path" {
+				name = to
+			}
+			key := p.In + " " + name
+			m[key] = p
+			keys = append(keys, key)
+		}
+		return m, keys
+	}
+	prevParams, prevKeys := params(prev, renamed)
+	nextParams, nextKeys := params(next, nil)
+
+	for _, key := range prevKeys {
+		p := prevParams[key]
+		n, ok := nextParams[key]
+		if ok && p.Name != n.Name {
+			self.report(false, key, "path parameter %s renamed to %s", p.Name, n.Name)
+		}
+		if !ok {
+			self.report(p.In == "path", key, "parameter removed")
+			continue
+		}
+		self.compareParam(key, p, n)
+	}
+	for _, key := range nextKeys {
+		if _, ok := prevParams[key]; ok {
+			continue
+		}
+		n := nextParams[key]
+		if n.Required || n.In == "path" {
+			self.report(true, key, "new required parameter")
+		} else {
+			self.report(false, key, "new optional parameter")
+		}
+	}
+
+	prevResp, nextResp := responsesOf(prev), responsesOf(next)
+	for _, code := range sortedKeys(prevResp) {
+		loc := "response " + code
+		n, ok := nextResp[code]
+		if !ok {
+			self.report(true, loc, "response removed")
+			continue
+		}
+		self.compareSchema(loc, prevResp[code].Schema, n.Schema, dirResponse, map[string]bool{})
+	}
+	for _, code := range sortedKeys(nextResp) {
+		if _, ok := prevResp[code]; !ok {
+			self.report(false, "response "+code, "response added")
+		}
+	}
+}
+
+func responsesOf(op *spec.Operation) map[string]spec.Response {
+	out := map[string]spec.Response{}
+	if op.Responses == nil {
+		return out
+	}
+	if op.Responses.Default != nil {
+		out["default"] = *op.Responses.Default
+	}
+	for code, r := range op.Responses.StatusCodeResponses {
+		out[fmt.Sprint(code)] = r
+	}
+	return out
+}
+
+func sortedKeys(m map[string]spec.Response) []string {
+	keys := []string{}
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (self *apiDiffer) compareParam(loc string, prev, next spec.Parameter) {
+	if !prev.Required && next.Required {
+		self.report(true, loc, "parameter became required")
+	} else if prev.Required && !next.Required {
+		self.report(false, loc, "parameter became optional")
+	}
+	if prev.Type != next.Type || prev.Format != next.Format {
+		self.report(true, loc, "type changed from %s to %s", typeName(prev.Type, prev.Format), typeName(next.Type, next.Format))
+	}
+	self.compareEnum(loc, prev.Enum, next.Enum, dirRequest)
+	if prev.Schema != nil || next.Schema != nil {
+		self.compareSchema(loc, prev.Schema, next.Schema, dirRequest, map[string]bool{})
+	}
+}
+
+func (self *apiDiffer) compareSchema(loc string, prev, next *spec.Schema, dir direction, seen map[string]bool) {
+	prev, prevRef := resolveSchema(self.prev, prev)
+	next, nextRef := resolveSchema(self.next, next)
+	if prev == nil && next == nil {
+		return
+	}
+	if prev == nil {
+		self.report(dir == dirRequest, loc, "body added")
+		return
+	}
+	if next == nil {
+		self.report(dir == dirResponse, loc, "body removed")
+		return
+	}
+
+	// guard recursive definitions
+	if prevRef != "" || nextRef != "" {
+		key := fmt.Sprintf("%s|%s|%d", prevRef, nextRef, dir)
+		if seen[key] {
+			return
+		}
+		seen[key] = true
+	}
+
+	pt, nt := strings.Join(prev.Type, ","), strings.Join(next.Type, ",")
+	if pt != nt || prev.Format != next.Format {
+		self.report(true, loc, "type changed from %s to %s", typeName(pt, prev.Format), typeName(nt, next.Format))
+		return
+	}
+	self.compareEnum(loc, prev.Enum, next.Enum, dir)
+
+	prevRequired, nextRequired := map[string]bool{}, map[string]bool{}
+	for _, r := range prev.Required {
+		prevRequired[r] = true
+	}
+	for _, r := range next.Required {
+		nextRequired[r] = true
+	}
+
+	names := []string{}
+	for name := range prev.Properties {
+		names = append(names, name)
+	}
+	for name := range next.Properties {
+		if _, ok := prev.Properties[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		floc := loc + "." + name
+		p, inPrev := prev.Properties[name]
+		n, inNext := next.Properties[name]
+		switch {
+		case !inNext:
+			self.report(dir == dirResponse, floc, "field removed")
+		case !inPrev:
+			if dir == dirRequest && nextRequired[name] {
+				self.report(true, floc, "new required field")
+			} else {
+				self.report(false, floc, "field added")
+			}
+		default:
+			if dir == dirRequest && !prevRequired[name] && nextRequired[name] {
+				self.report(true, floc, "field became required")
+			}
+			if dir == dirResponse && prevRequired[name] && !nextRequired[name] {
+				self.report(true, floc, "field is no longer always present")
+			}
+			self.compareSchema(floc, &p, &n, dir, seen)
+		}
+	}
+
+	if prev.Items != nil || next.Items != nil {
+		var pi, ni *spec.Schema
+		if prev.Items != nil {
+			pi = prev.Items.Schema
+		}
+		if next.Items != nil {
+			ni = next.Items.Schema
+		}
+		self.compareSchema(loc+"[]", pi, ni, dir, seen)
+	}
+}
+
+// compareEnum flags narrowed request enums and widened response enums.
+func (self *apiDiffer) compareEnum(loc string, prev, next []interface{}, dir direction) {
+	if len(prev) == 0 && len(next) == 0 {
+		return
+	}
+	in := func(v interface{}, list []interface{}) bool {
+		for _, item := range list {
+			if fmt.Sprint(item) == fmt.Sprint(v) {
+				return true
+			}
+		}
+		return false
+	}
+
+	removed, added := []string{}, []string{}
+	for _, v := range prev {
+		if !in(v, next) {
+			removed = append(removed, fmt.Sprint(v))
+		}
+	}
+	for _, v := range next {
+		if !in(v, prev) {
+			added = append(added, fmt.Sprint(v))
+		}
+	}
+
+	if len(prev) == 0 {
+		self.report(dir == dirRequest, loc, "values restricted to enum")
+		return
+	}
+	if len(next) == 0 {
+		self.report(dir == dirResponse, loc, "enum restriction removed")
+		return
+	}
+	if len(removed) > 0 {
+		self.report(dir == dirRequest, loc, "enum values removed: %s", strings.Join(removed, ", "))
+	}
+	if len(added) > 0 {
+		self.report(dir == dirResponse, loc, "enum values added: %s", strings.Join(added, ", "))
+	}
+}
+
+func resolveSchema(sw *spec.Swagger, schema *spec.Schema) (*spec.Schema, string) {
+	ref := ""
+	for i := 0; schema != nil && i < 32; i++ {
+		r := schema.Ref.String()
+		if r == "" {
+			break
+		}
+		ref = r
+		def, ok := sw.Definitions[strings.TrimPrefix(r, "#/definitions/")]
+		if !ok {
+			return nil, ref
+		}
+		schema = &def
+	}
+	return schema, ref
+}
+
+func typeName(tpe, format string) string {
+	if tpe == "" {
+		tpe = "any"
+	}
+	if format != "" {
+		return tpe + "(" + format + ")"
+	}
+	return tpe
+}
+
+func renderAPIChanges(w io.Writer, format string, changes []apiChange) error {
+	breaking := 0
+	for _, c := range changes {
+		if c.Breaking {
+			breaking++
+		}
+	}
+
+	switch strings.ToLower(format) {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetEscapeHTML(false)
+		enc.SetIndent("", "  ")
+		return enc.Encode(struct {
+			Breaking int         `json:"breaking"`
+			Changes  []apiChange `json:"changes"`
+		}{breaking, append([]apiChange{}, changes...)})
+	case "", "text":
+		for _, c := range changes {
+			mark := "         "
+			if c.Breaking {
+				mark = "BREAKING "
+			}
+			loc := ""
+			if c.Location != "" {
+				loc = " " + c.Location + ":"
+			}
+			if _, err := fmt.Fprintf(w, "%s%s%s %s\n", mark, c.Operation, loc, c.Message); err != nil {
+				return err
+			}
+		}
+		_, err := fmt.Fprintf(w, "%d change(s), %d breaking\n", len(changes), breaking)
+		return err
+	}
+	return fmt.Errorf("unknown diff format %q", format)
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/go-openapi/spec"
+)
+
+func TestCompareAPIs(t *testing.T) {
+	cases := []struct {
+		name       string
+		prev, next string
+		changes    []string
+	}{
+		{
+			name:    "unchanged",
+			prev:    `{"paths": {"/users/{id}": {"get": {"operationId": "getUser", "parameters": [{"name": "id", "in": "path", "required": true, "type": "string"}]}}}}`,
+			next:    `{"paths": {"/users/{id}": {"get": {"operationId": "getUser", "parameters": [{"name": "id", "in": "path", "required": true, "type": "string"}]}}}}`,
+			changes: []string{},
+		},
+		{
+			name: "path parameter renamed",
+			prev: `{"paths": {"/users/{id}": {"get": {"operationId": "getUser", "parameters": [{"name": "id", "in": "path", "required": true, "type": "string"}]}}}}`,
+			next: `{"paths": {"/users/{uid}": {"get": {"operationId": "getUser", "parameters": [{"name": "uid", "in": "path", "required": true, "type": "string"}]}}}}`,
+			changes: []string{
+				"ok GET /users/{uid} path uid: path parameter id renamed to uid",
+			},
+		},
+		{
+			name: "operation moved",
+			prev: `{"paths": {"/users/{id}": {"get": {"operationId": "getUser"}}}}`,
+			next: `{"paths": {"/people/{id}": {"get": {"operationId": "getUser"}}}}`,
+			changes: []string{
+				"breaking GET /users/{id}: operation getUser moved to GET /people/{id}",
+			},
+		},
+		{
+			name: "operations removed and added",
+			prev: `{"paths": {"/users": {"get": {}}}}`,
+			next: `{"paths": {"/users": {"post": {}}}}`,
+			changes: []string{
+				"breaking GET /users: operation removed",
+				"ok POST /users: operation added",
+			},
+		},
+		{
+			name: "parameters",
+			prev: `{"paths": {"/users": {"get": {"parameters": [
+				{"name": "q", "in": "query", "type": "string"},
+				{"name": "limit", "in": "query", "type": "integer", "required": true}]}}}}`,
+			next: `{"paths": {"/users": {"get": {"parameters": [
+				{"name": "q", "in": "query", "type": "string", "required": true},
+				{"name": "limit", "in": "query", "type": "string"},
+				{"name": "page", "in": "query", "type": "integer"},
+				{"name": "X-Key", "in": "header", "type": "string", "required": true}]}}}}`,
+			changes: []string{
+				"breaking GET /users query q: parameter became required",
+				"ok GET /users query limit: parameter became optional",
+				"breaking GET /users query limit: type changed from integer to string",
+				"ok GET /users query page: new optional parameter",
+				"breaking GET /users header X-Key: new required parameter",
+			},
+		},
+		{
+			name: "request enum",
+			prev: `{"paths": {"/users": {"get": {"parameters": [{"name": "sort", "in": "query", "type": "string", "enum": ["name", "age"]}]}}}}`,
+			next: `{"paths": {"/users": {"get": {"parameters": [{"name": "sort", "in": "query", "type": "string", "enum": ["name", "created"]}]}}}}`,
+			changes: []string{
+				"breaking GET /users query sort: enum values removed: age",
+				"ok GET /users query sort: enum values added: created",
+			},
+		},
+		{
+			name: "response enum",
+			prev: `{"paths": {"/users": {"get": {"responses": {"200": {"description": "ok", "schema": {"type": "string", "enum": ["a", "b"]}}}}}}}`,
+			next: `{"paths": {"/users": {"get": {"responses": {"200": {"description": "ok", "schema": {"type": "string", "enum": ["a", "c"]}}}}}}}`,
+			changes: []string{
+				"ok GET /users response 200: enum values removed: b",
+				"breaking GET /users response 200: enum values added: c",
+			},
+		},
+		{
+			name: "enum restriction",
+			prev: `{"paths": {"/users": {"get": {
+				"parameters": [{"name": "sort", "in": "query", "type": "string"}],
+				"responses": {"200": {"description": "ok", "schema": {"type": "string", "enum": ["a"]}}}}}}}`,
+			next: `{"paths": {"/users": {"get": {
+				"parameters": [{"name": "sort", "in": "query", "type": "string", "enum": ["name"]}],
+				"responses": {"200": {"description": "ok", "schema": {"type": "string"}}}}}}}`,
+			changes: []string{
+				"breaking GET /users query sort: values restricted to enum",
+				"breaking GET /users response 200: enum restriction removed",
+			},
+		},
+		{
+			name: "fields",
+			prev: `{"paths": {"/users": {"post": {
+				"parameters": [{"name": "body", "in": "body", "schema": {"$ref": "#/definitions/req"}}],
+				"responses": {"201": {"description": "ok", "schema": {"$ref": "#/definitions/user"}}}}}},
+				"definitions": {
+					"req": {"type": "object", "properties": {"name": {"type": "string"}}},
+					"user": {"type": "object", "required": ["id"], "properties": {"id": {"type": "string"}, "age": {"type": "integer"}}}}}`,
+			next: `{"paths": {"/users": {"post": {
+				"parameters": [{"name": "body", "in": "body", "schema": {"$ref": "#/definitions/req"}}],
+				"responses": {"201": {"description": "ok", "schema": {"$ref": "#/definitions/user"}}}}}},
+				"definitions": {
+					"req": {"type": "object", "required": ["name", "email"], "properties": {"name": {"type": "string"}, "email": {"type": "string"}}},
+					"user": {"type": "object", "properties": {"id": {"type": "string"}, "tags": {"type": "array", "items": {"type": "string"}}}}}}`,
+			changes: []string{
+				"breaking POST /users body body.email: new required field",
+				"breaking POST /users body body.name: field became required",
+				"breaking POST /users response 201.age: field removed",
+				"breaking POST /users response 201.id: field is no longer always present",
+				"ok POST /users response 201.tags: field added",
+			},
+		},
+		{
+			name: "responses",
+			prev: `{"paths": {"/users": {"get": {"responses": {"200": {"description": "ok"}, "404": {"description": "missing"}}}}}}`,
+			next: `{"paths": {"/users": {"get": {"responses": {"200": {"description": "ok"}, "default": {"description": "error"}}}}}}`,
+			changes: []string{
+				"breaking GET /users response 404: response removed",
+				"ok GET /users response default: response added",
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := []string{}
+			for _, ch := range compareAPIs(testSwagger(t, c.prev), testSwagger(t, c.next)) {
+				kind := "ok"
+				if ch.Breaking {
+					kind = "breaking"
+				}
+				loc := ch.Operation
+				if ch.Location != "" {
+					loc += " " + ch.Location
+				}
+				got = append(got, fmt.Sprintf("%s %s: %s", kind, loc, ch.Message))
+			}
+			if !reflect.DeepEqual(got, c.changes) {
+				t.Errorf("got changes\n%q\nwant\n%q", got, c.changes)
+			}
+		})
+	}
+}
+
+func testSwagger(t *testing.T, doc string) *spec.Swagger {
+	t.Helper()
+	sw := new(spec.Swagger)
+	if err := json.Unmarshal([]byte(doc), sw); err != nil {
+		t.Fatal(err)
+	}
+	return sw
+}
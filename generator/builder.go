@@ -0,0 +1,497 @@
+package generator
+
+import (
+	"go/ast"
+	"go/token"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-openapi/spec"
+)
+
+type builder struct {
+	input *spec.Swagger
+	ctx   *Scanner
+	diags *Diagnostics
+
+	// bound records which route produced each "METHOD path" operation
+	bound map[string]*Route
+}
+
+func build(ctx *Scanner, input *spec.Swagger, diags *Diagnostics) (*spec.Swagger, error) {
+	if input == nil {
+		input = new(spec.Swagger)
+		input.Swagger = "2.0"
+	}
+
+	m, input, err := newMerger(input, diags)
+	if err != nil {
+		return nil, err
+	}
+
+	if input.Paths == nil {
+		input.Paths = new(spec.Paths)
+	}
+	if input.Definitions == nil {
+		input.Definitions = make(map[string]spec.Schema)
+	}
+	if input.Responses == nil {
+		input.Responses = make(map[string]spec.Response)
+	}
+	if input.Extensions == nil {
+		input.Extensions = make(spec.Extensions)
+	}
+
+	b := builder{
+		input: input,
+		ctx:   ctx,
+		diags: diags,
+		bound: map[string]*Route{},
+	}
+
+	b.buildMeta()
+	b.buildRoute()
+	b.checkPaths()
+	b.buildReq()
+	b.buildAns()
+
+	return m.merge(b.input)
+}
+
+func (self *builder) buildMeta() {
+	metas := [][2]string{}
+	for _, meta := range self.ctx.metas {
+		var key, value string
+		for _, lines := range meta.Comments.List {
+			for _, line := range strings.Split(lines.Text, "\n") {
+				line = strings.TrimLeft(line, "/")
+				pos := strings.Index(line, ":")
+				if pos != -1 {
+					if key != "" {
+						metas = append(metas, [2]string{key, value})
+					}
+					key = strings.TrimSpace(line[:pos])
+					value = strings.TrimSpace(line[pos+1:])
+				} else {
+					if line != "" {
+						value += "\n" + line
+					}
+				}
+			}
+		}
+		if key != "" {
+			metas = append(metas, [2]string{key, value})
+		}
+	}
+
+	if self.input.Info == nil {
+		self.input.Info = &spec.Info{}
+	}
+	for _, kv := range metas {
+		k, v := kv[0], kv[1]
+		switch strings.ToLower(strings.TrimSpace(k)) {
+		case "title":
+			self.input.Info.Title = v
+		case "version":
+			self.input.Info.Version = v
+		case "schemes":
+			vv := strings.Split(v, ",")
+			for k, v := range vv {
+				vv[k] = strings.TrimSpace(v)
+			}
+			self.input.Schemes = vv
+		case "host":
+			self.input.Host = v
+		case "basepath":
+			self.input.BasePath = v
+		case "consumes":
+			vv := strings.Split(v, ",")
+			for k, v := range vv {
+				vv[k] = strings.TrimSpace(v)
+			}
+			self.input.Consumes = vv
+		case "produces":
+			vv := strings.Split(v, ",")
+			for k, v := range vv {
+				vv[k] = strings.TrimSpace(v)
+			}
+			self.input.Produces = vv
+		}
+	}
+}
+
+func (self *builder) buildRoute() {
+	for _, r := range self.ctx.Routes() {
+		if self.input.Paths.Paths == nil {
+			self.input.Paths.Paths = make(map[string]spec.PathItem)
+		}
+
+		path := self.ctx.RoutePath(r)
+		pthObj := self.input.Paths.Paths[path]
+
+		key := strings.ToUpper(r.Method) + " " + path
+		if existing := self.routerOperator(path, r.Method); existing != nil && existing.ID != r.ID {
+			if other, ok := self.bound[key]; ok {
+				self.diags.Errorf(r.Pos, CodeRouteConflict, "route %s: %s is already bound to route %s at %s", r.ID, key, other.ID, other.Pos).Relate(other.Pos)
+				continue
+			}
+			self.diags.Warnf(r.Pos, CodeRouteConflict, "route %s: replaces operation %q for %s from the input spec", r.ID, existing.ID, key)
+		}
+		self.bound[key] = r
+
+		op := new(spec.Operation)
+		op.ID = r.ID
+		op.AddExtension(generatedExt, true)
+
+		switch strings.ToUpper(r.Method) {
+		case "GET":
+			if pthObj.Get != nil {
+				if r.ID == pthObj.Get.ID {
+					op = pthObj.Get
+				} else {
+					pthObj.Get = op
+				}
+			} else {
+				pthObj.Get = op
+			}
+
+		case "POST":
+			if pthObj.Post != nil {
+				if r.ID == pthObj.Post.ID {
+					op = pthObj.Post
+				} else {
+					pthObj.Post = op
+				}
+			} else {
+				pthObj.Post = op
+			}
+
+		case "PUT":
+			if pthObj.Put != nil {
+				if r.ID == pthObj.Put.ID {
+					op = pthObj.Put
+				} else {
+					pthObj.Put = op
+				}
+			} else {
+				pthObj.Put = op
+			}
+
+		case "PATCH":
+			if pthObj.Patch != nil {
+				if r.ID == pthObj.Patch.ID {
+					op = pthObj.Patch
+				} else {
+					pthObj.Patch = op
+				}
+			} else {
+				pthObj.Patch = op
+			}
+
+		case "HEAD":
+			if pthObj.Head != nil {
+				if r.ID == pthObj.Head.ID {
+					op = pthObj.Head
+				} else {
+					pthObj.Head = op
+				}
+			} else {
+				pthObj.Head = op
+			}
+
+		case "DELETE":
+			if pthObj.Delete != nil {
+				if r.ID == pthObj.Delete.ID {
+					op = pthObj.Delete
+				} else {
+					pthObj.Delete = op
+				}
+			} else {
+				pthObj.Delete = op
+			}
+
+		case "OPTIONS":
+			if pthObj.Options != nil {
+				if r.ID == pthObj.Options.ID {
+					op = pthObj.Options
+				} else {
+					pthObj.Options = op
+				}
+			} else {
+				pthObj.Options = op
+			}
+
+		default:
+			self.diags.Errorf(r.Pos, CodeUnknownMethod, "route %s: unknown HTTP method %s", r.ID, r.Method)
+			continue
+		}
+
+		if r.Remaining == nil {
+			r.Remaining = new(ast.CommentGroup)
+		}
+		for _, c := range r.Remaining.List {
+			for _, line := range strings.Split(c.Text, "\n") {
+				if op.Summary == "" {
+					op.Summary = self.commentLineClear(line)
+				} else {
+					if op.Description != "" {
+						op.Description += "\n"
+					}
+					op.Description += self.commentLineClear(line)
+				}
+			}
+		}
+
+		op.Tags = self.ctx.RouteTags(r)
+		self.input.Paths.Paths[path] = pthObj
+	}
+}
+
+func (self *builder) buildReq() {
+	for _, req := range self.ctx.Requests() {
+		route, ok := self.ctx.routes[req.ID]
+		if !ok {
+			self.diags.Errorf(req.Pos(), CodeUnknownRoute, "swag:req %s: no swag:route with this id", req.ID)
+			continue
+		}
+
+		path := self.ctx.RoutePath(route)
+		// a route that lost its path and method to another has no operation
+		op := self.routerOperator(path, route.Method)
+		if op != nil && op.ID == route.ID {
+
+			fields := map[string]spec.Parameter{}
+			for _, p := range req.frag.Params {
+				if p.Param.In == "query" {
+					fields[p.Param.Name] = p.Param
+				}
+			}
+			inPath := map[string]bool{}
+			for _, name := range PathParams(path) {
+				inPath[name] = true
+				param := spec.PathParam(name).Typed("string", "")
+				// a request field of the same name types and constrains it
+				if field, ok := fields[name]; ok {
+					param = spec.PathParam(name).Typed(field.Type, field.Format).WithDescription(field.Description)
+					param.CommonValidations = field.CommonValidations
+				}
+				op.AddParam(generatedParam(param))
+			}
+
+			// query parameters only for GET, everything else has a body
+			for i := range req.frag.Params {
+				param := req.frag.Params[i].Param
+				if param.In == "header" || route.Method == "GET" && !inPath[param.Name] {
+					op.AddParam(generatedParam(&param))
+				}
+			}
+
+			if route.Method != "GET" {
+				self.useSchema(req)
+
+				commentlines := []string{}
+				for _, c := range req.Comments.List {
+					for _, line := range strings.Split(c.Text, "\n") {
+						commentlines = append(commentlines, self.commentLineClear(line))
+					}
+				}
+
+				desc := " "
+				if len(commentlines) > 1 {
+					desc = strings.Join(commentlines[1:], "\n")
+				}
+
+				body := spec.BodyParam("Body", spec.RefSchema("#/definitions/"+req.Name))
+				body.Description = desc
+				op.AddParam(generatedParam(body))
+			}
+		}
+	}
+}
+
+func (self *builder) buildAns() {
+	for _, ans := range self.ctx.Responses() {
+
+		route, ok := self.ctx.routes[ans.ID]
+		if !ok {
+			self.diags.Errorf(ans.Pos(), CodeUnknownRoute, "swag:ans %s %d: no swag:route with this id", ans.ID, ans.Code)
+			continue
+		}
+
+		op := self.routerOperator(self.ctx.RoutePath(route), route.Method)
+		if op != nil && op.ID == route.ID {
+
+			self.useSchema(ans)
+
+			commentlines := []string{}
+			for _, c := range ans.Comments.List {
+				for _, line := range strings.Split(c.Text, "\n") {
+					commentlines = append(commentlines, self.commentLineClear(line))
+				}
+			}
+
+			desc := " "
+			if len(commentlines) > 1 {
+				desc = strings.Join(commentlines[1:], "\n")
+			}
+
+			response := spec.NewResponse()
+			response.WithSchema(spec.RefSchema("#/definitions/" + ans.Name))
+			response.WithDescription(desc)
+			response.AddExtension(generatedExt, true)
+			op.RespondsWith(ans.Code, response)
+		}
+	}
+}
+
+// useSchema lays the schema of decl over its definition from the input
+// spec and reports what building the schema turned up.
+func (self *builder) useSchema(decl *Decl) {
+	self.diags.Append(decl.frag.SchemaDiags...)
+
+	schema := self.input.Definitions[decl.Name]
+	if prev, err := genericJSON(schema); err == nil {
+		if next, err := genericJSON(decl.frag.Schema); err == nil {
+			prevObj, _ := prev.(map[string]interface{})
+			nextObj, _ := next.(map[string]interface{})
+			if prevObj != nil && nextObj != nil {
+				mergeUpdate(prevObj, nextObj)
+				fromGeneric(prevObj, &schema)
+			}
+		}
+	}
+	schema.AddExtension(generatedExt, true)
+	self.input.Definitions[decl.Name] = schema
+}
+
+// checkPaths reports paths that a router could not tell apart: templated
+// segments that only differ by parameter name, or a trailing slash.
+func (self *builder) checkPaths() {
+	paths := []string{}
+	for p := range self.input.Paths.Paths {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	pathPos := map[string]token.Position{}
+	for _, r := range self.ctx.Routes() {
+		p := self.ctx.RoutePath(r)
+		if _, ok := pathPos[p]; !ok {
+			pathPos[p] = r.Pos
+		}
+	}
+
+	templated := map[string]string{}
+	slashless := map[string]string{}
+	for _, p := range paths {
+		norm := NormalizePath(p)
+		if other, ok := templated[norm]; ok {
+			self.diags.Errorf(pathPos[p], CodeAmbiguousPath, "path %s is ambiguous with %s", p, other).Relate(pathPos[other])
+		} else {
+			templated[norm] = p
+		}
+
+		trimmed := strings.TrimSuffix(norm, "/")
+		if other, ok := slashless[trimmed]; ok && NormalizePath(other) != norm {
+			self.diags.Warnf(pathPos[p], CodeTrailingSlash, "path %s differs from %s only by a trailing slash", p, other).Relate(pathPos[other])
+		} else if !ok {
+			slashless[trimmed] = p
+		}
+	}
+}
+
+func generatedParam(p *spec.Parameter) *spec.Parameter {
+	p.AddExtension(generatedExt, true)
+	return p
+}
+
+func (self builder) commentLineClear(line string) string {
+	line = strings.TrimSpace(line)
+	if strings.HasPrefix(line, "//") {
+		line = line[2:]
+	}
+	line = strings.TrimSpace(line)
+	return line
+}
+
+func (self builder) routerOperator(path, method string) *spec.Operation {
+	var op *spec.Operation
+	if pthObj, ok := self.input.Paths.Paths[path]; ok {
+		switch strings.ToUpper(method) {
+		case "GET":
+			op = pthObj.Get
+		case "POST":
+			op = pthObj.Post
+		case "PUT":
+			op = pthObj.Put
+		case "PATCH":
+			op = pthObj.Patch
+		case "HEAD":
+			op = pthObj.Head
+		case "DELETE":
+			op = pthObj.Delete
+		case "OPTIONS":
+			op = pthObj.Options
+		}
+	}
+	return op
+}
+
+// parseHeaderTag is the header a field is sent in, if it has a header tag.
+func parseHeaderTag(field *ast.Field) string {
+	if field.Tag == nil {
+		return ""
+	}
+	tv, err := strconv.Unquote(field.Tag.Value)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(reflect.StructTag(tv).Get("header"))
+}
+
+// hasJsonOption reports whether the json tag of field carries option, e.g. omitempty.
+func hasJsonOption(field *ast.Field, option string) bool {
+	if field.Tag == nil {
+		return false
+	}
+	tv, err := strconv.Unquote(field.Tag.Value)
+	if err != nil {
+		return false
+	}
+	for _, opt := range strings.Split(reflect.StructTag(tv).Get("json"), ",")[1:] {
+		if strings.TrimSpace(opt) == option {
+			return true
+		}
+	}
+	return false
+}
+
+func parseJsonTags(field *ast.Field) (name string, ignore bool) {
+	if len(field.Names) > 0 {
+		name = field.Names[0].Name
+	}
+	if field.Tag == nil || len(strings.TrimSpace(field.Tag.Value)) == 0 {
+		return name, false
+	}
+
+	tv, err := strconv.Unquote(field.Tag.Value)
+	if err != nil {
+		return name, false
+	}
+
+	if strings.TrimSpace(tv) != "" {
+		st := reflect.StructTag(tv)
+		jsonParts := strings.Split(st.Get("json"), ",")
+		switch jsonParts[0] {
+		case "-":
+			return name, true
+		case "":
+			return name, false
+		default:
+			return jsonParts[0], false
+		}
+	}
+	return name, false
+}
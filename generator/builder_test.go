@@ -0,0 +1,50 @@
+package generator
+
+import (
+	"testing"
+
+	"golang.org/x/tools/go/packages"
+)
+
+func TestRouteConflict(t *testing.T) {
+	scans, err := scanPackages([]*packages.Package{testPackage(t, "testdata/conflict", "example.com/conflict")}, nil, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	diags := new(Diagnostics)
+	sw, err := build(merged(scans, diags), nil, diags)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conflicts := 0
+	for _, d := range diags.List() {
+		if d.Code == CodeRouteConflict {
+			conflicts++
+		} else if d.Severity == SeverityError {
+			t.Errorf("unexpected diagnostic %s", d)
+		}
+	}
+	if conflicts != 1 {
+		t.Errorf("got %d route conflicts, want 1", conflicts)
+	}
+
+	doc, err := genericJSON(sw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"$.paths['/users'].post.operationId":                  `["createUser"]`,
+		"$.paths['/users'].post.tags":                         `[["users"]]`,
+		"$.paths['/users'].post.parameters[*].schema['$ref']": `["#/definitions/createUser"]`,
+		"$.paths['/users'].post.responses['201'].description": `["created"]`,
+		"$.paths['/users'].post.responses['202']":             `[]`,
+		"$.definitions.createAdmin":                           `[]`,
+		"$.definitions['createAdmin-202']":                    `[]`,
+	}
+	for expr, w := range want {
+		if got := selectJSON(t, doc, expr); got != w {
+			t.Errorf("%s: got %s, want %s", expr, got, w)
+		}
+	}
+}
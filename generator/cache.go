@@ -0,0 +1,178 @@
+package generator
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// cacheFormat is bumped whenever pkgScan or fragment change shape.
+const cacheFormat = "go2swag-cache-7"
+
+// scanCache keeps package scans on disk between runs. An entry is keyed by
+// the package files, the keys of every package it imports, directly or not,
+// go.mod and go.sum, the toolchain and the go2swag binary, so a hit is only
+// ever used when scanning again would produce the same result.
+type scanCache struct {
+	dir    string
+	base   []byte
+	pinned []string
+	keys   map[string]string
+}
+
+func newScanCache(dir, loadDir string) *scanCache {
+	h := sha256.New()
+	fmt.Fprintln(h, cacheFormat, runtime.Version())
+	if exe, err := os.Executable(); err == nil {
+		if fi, err := os.Stat(exe); err == nil {
+			fmt.Fprintln(h, exe, fi.Size(), fi.ModTime().UnixNano())
+		}
+	}
+	if mod := goModFile(loadDir); mod != "" {
+		hashFile(h, mod)
+		hashFile(h, filepath.Join(filepath.Dir(mod), "go.sum"))
+	}
+
+	// files below GOROOT are pinned by the toolchain, those in the module
+	// cache by go.sum
+	pinned := []string{filepath.Join(runtime.GOROOT(), "src") + string(filepath.Separator)}
+	if modCache := os.Getenv("GOMODCACHE"); modCache != "" {
+		pinned = append(pinned, filepath.Clean(modCache)+string(filepath.Separator))
+	}
+	gopath := os.Getenv("GOPATH")
+	if home, err := os.UserHomeDir(); err == nil && gopath == "" {
+		gopath = filepath.Join(home, "go")
+	}
+	for _, dir := range filepath.SplitList(gopath) {
+		pinned = append(pinned, filepath.Join(dir, "pkg", "mod")+string(filepath.Separator))
+	}
+
+	return &scanCache{
+		dir:    dir,
+		base:   h.Sum(nil),
+		pinned: pinned,
+		keys:   map[string]string{},
+	}
+}
+
+func (self *scanCache) key(pkg *packages.Package) string {
+	if key, ok := self.keys[pkg.PkgPath]; ok {
+		return key
+	}
+	// guards against import cycles in broken code
+	self.keys[pkg.PkgPath] = ""
+
+	h := sha256.New()
+	h.Write(self.base)
+	fmt.Fprintln(h, pkg.PkgPath)
+	files := append([]string{}, pkg.GoFiles...)
+	sort.Strings(files)
+	for _, name := range files {
+		fmt.Fprintln(h, name)
+		hashFile(h, name)
+	}
+
+	// schemas are built from the types of any import, whether it is scanned
+	// or not, so all of them count but those whose files cannot change
+	paths := []string{}
+	for path := range pkg.Imports {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		imp := pkg.Imports[path]
+		if self.isPinned(imp) {
+			fmt.Fprintln(h, path)
+		} else {
+			fmt.Fprintln(h, path, self.key(imp))
+		}
+	}
+
+	key := hex.EncodeToString(h.Sum(nil))
+	self.keys[pkg.PkgPath] = key
+	return key
+}
+
+// isPinned reports whether the files of pkg are in GOROOT or the module
+// cache, where they only change along with the toolchain or go.sum.
+func (self *scanCache) isPinned(pkg *packages.Package) bool {
+	files := append(append([]string{}, pkg.GoFiles...), pkg.OtherFiles...)
+	if len(files) == 0 {
+		return true
+	}
+	for _, prefix := range self.pinned {
+		if strings.HasPrefix(files[0], prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func (self *scanCache) file(pkg *packages.Package) string {
+	key := self.key(pkg)
+	return filepath.Join(self.dir, key[:2], key+".json")
+}
+
+func (self *scanCache) get(pkg *packages.Package) (*pkgScan, bool) {
+	b, err := ioutil.ReadFile(self.file(pkg))
+	if err != nil {
+		return nil, false
+	}
+	ps := new(pkgScan)
+	if err := json.Unmarshal(b, ps); err != nil || ps.Path != pkg.PkgPath {
+		return nil, false
+	}
+	return ps, true
+}
+
+// put stores ps for pkg, as listed by metaLoadMode. Failing to write the
+// cache only costs time on the next run, so errors are ignored.
+func (self *scanCache) put(pkg *packages.Package, ps *pkgScan) {
+	if len(pkg.Errors) > 0 {
+		return
+	}
+	b, err := json.Marshal(ps)
+	if err != nil {
+		return
+	}
+
+	name := self.file(pkg)
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		return
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(name), "tmp-")
+	if err != nil {
+		return
+	}
+	_, err = tmp.Write(b)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return
+	}
+	if err := os.Rename(tmp.Name(), name); err != nil {
+		os.Remove(tmp.Name())
+	}
+}
+
+func hashFile(h hash.Hash, name string) {
+	b, err := ioutil.ReadFile(name)
+	if err != nil {
+		fmt.Fprintln(h, "missing")
+		return
+	}
+	fmt.Fprintln(h, len(b))
+	h.Write(b)
+}
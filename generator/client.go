@@ -0,0 +1,215 @@
+package generator
+
+import (
+	"bytes"
+	"go/format"
+	"strings"
+	"text/template"
+
+	"github.com/go-openapi/spec"
+)
+
+// GoClient renders a Go client of package pkg with one method per route of
+// the scan. Methods take the swag:req type, fill path, query and header
+// parameters from it and decode each declared status into its swag:ans
+// type; both are imported from where they are declared. Routes the client
+// cannot express are left out and reported.
+func GoClient(sw *spec.Swagger, scan *Scanner, pkg string, diags *Diagnostics) ([]byte, error) {
+	imports := newGoImports("bytes", "context", "encoding", "json", "fmt", "io", "ioutil", "http", "url", "reflect", "strings", "isZero", "paramValue", "decode")
+	routes := goRoutes(scan, imports, diags)
+
+	var buf bytes.Buffer
+	err := goClientTemplate.Execute(&buf, map[string]interface{}{
+		"Package":  pkg,
+		"BasePath": strings.TrimSuffix(sw.BasePath, "/"),
+		"Imports":  imports.list(),
+		"Routes":   routes,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return format.Source(buf.Bytes())
+}
+
+var goClientTemplate = template.Must(template.New("").Funcs(template.FuncMap{
+	"comment": func(s string) string {
+		return strings.Replace(s, "\n", "\n// ", -1)
+	},
+}).Parse(`// Code generated by go2swag. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+	"bytes"
+	"context"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+{{range .Imports}}
+	{{.Alias}} {{printf "%q" .Path}}{{end}}
+)
+
+// BasePath is prepended to the path of every route.
+const BasePath = {{printf "%q" .BasePath}}
+
+// Client calls the API at BaseURL.
+type Client struct {
+	// BaseURL is the scheme and host of the API, e.g. http://localhost:8080.
+	BaseURL string
+	// HTTPClient sends the requests, http.DefaultClient if nil.
+	HTTPClient *http.Client
+	// Header is sent with every request, e.g. for authentication.
+	Header http.Header
+}
+
+// New returns a client of the API at baseURL.
+func New(baseURL string) *Client {
+	return &Client{BaseURL: baseURL}
+}
+
+// Error is returned for a response whose status the route does not declare.
+type Error struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("unexpected response %d %s: %s", e.StatusCode, http.StatusText(e.StatusCode), bytes.TrimSpace(e.Body))
+}
+{{range .Routes}}{{$route := .}}
+// {{.Name}}Result is the response of {{.Name}}; the field of the
+// received status is set.
+type {{.Name}}Result struct {
+	StatusCode int
+	Header     http.Header
+{{- range .Responses}}
+	Status{{.Status}} *{{.Type}}{{end}}
+}
+
+// {{.Name}} sends {{.Method}} {{.Path}}.{{if .Comment}}
+//
+// {{comment .Comment}}{{end}}
+func (c *Client) {{.Name}}(ctx context.Context{{range .Args}}, {{.Field}} string{{end}}{{if .Request}}, req *{{.Request}}{{end}}) (*{{.Name}}Result, error) {
+{{- if .Request}}
+	if req == nil {
+		req = new({{.Request}})
+	}{{end}}
+	path := {{printf "%q" .Path}}
+{{- range .Args}}
+	path = strings.Replace(path, {{printf "%q" .Placeholder}}, url.PathEscape({{.Field}}), 1){{end}}
+{{- range .PathFields}}
+	path = strings.Replace(path, {{printf "%q" .Placeholder}}, url.PathEscape(paramValue(req.{{.Field}})), 1){{end}}
+	query := url.Values{}
+{{- range .Query}}
+	if !isZero(req.{{.Field}}) {
+		query.Set({{printf "%q" .Name}}, paramValue(req.{{.Field}}))
+	}{{end}}
+	header := http.Header{}
+{{- range .Header}}
+	if !isZero(req.{{.Field}}) {
+		header.Set({{printf "%q" .Name}}, paramValue(req.{{.Field}}))
+	}{{end}}
+
+	res, err := c.do(ctx, {{printf "%q" .Method}}, path, query, header, {{if .Body}}req{{else}}nil{{end}})
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	out := &{{.Name}}Result{StatusCode: res.StatusCode, Header: res.Header}
+	switch res.StatusCode {
+{{- range .Responses}}
+	case {{.Status}}:
+		out.Status{{.Status}} = new({{.Type}})
+		err = decode(res, out.Status{{.Status}}){{end}}
+	default:
+{{- if not .Responses}}
+		if res.StatusCode >= 200 && res.StatusCode < 300 {
+			break
+		}{{end}}
+		body, _ := ioutil.ReadAll(res.Body)
+		err = &Error{StatusCode: res.StatusCode, Header: res.Header, Body: body}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+{{end}}
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, header http.Header, body interface{}) (*http.Response, error) {
+	var payload io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		payload = bytes.NewReader(b)
+	}
+
+	u := strings.TrimSuffix(c.BaseURL, "/") + BasePath + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	req, err := http.NewRequest(method, u, payload)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	for k, v := range c.Header {
+		req.Header[k] = v
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+
+	hc := c.HTTPClient
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+	return hc.Do(req)
+}
+
+// decode reads a JSON response body into v, an empty body leaves v alone.
+func decode(res *http.Response, v interface{}) error {
+	err := json.NewDecoder(res.Body).Decode(v)
+	if err == io.EOF {
+		return nil
+	}
+	return err
+}
+
+func isZero(v interface{}) bool {
+	rv := reflect.ValueOf(v)
+	return !rv.IsValid() || rv.IsZero()
+}
+
+// paramValue formats a path, query or header parameter, preferring the
+// text form of types like time.Time.
+func paramValue(v interface{}) string {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return ""
+		}
+		rv = rv.Elem()
+	}
+	v = rv.Interface()
+	if m, ok := v.(encoding.TextMarshaler); ok {
+		if b, err := m.MarshalText(); err == nil {
+			return string(b)
+		}
+	}
+	return fmt.Sprint(v)
+}
+`))
@@ -0,0 +1,197 @@
+package generator
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestGoCodeOnePackage generates the client, server and validator into one
+// package, the way a service that both serves and calls its API would, and
+// vets the result.
+func TestGoCodeOnePackage(t *testing.T) {
+	gobin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go command not found")
+	}
+	sw, scan := testSpec(t, "testdata/api", "example.com/codegen/api")
+
+	dir, err := ioutil.TempDir("", "go2swag-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	write := func(name string, b []byte) {
+		name = filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(name, b, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("go.mod", []byte("module example.com/codegen\n\ngo 1.13\n"))
+	src, err := ioutil.ReadFile("testdata/api/api.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	write("api/api.go", src)
+
+	gens := []struct {
+		name string
+		gen  func(*Diagnostics) ([]byte, error)
+	}{
+		{"client.go", func(diags *Diagnostics) ([]byte, error) { return GoClient(sw, scan, "gen", diags) }},
+		{"server.go", func(diags *Diagnostics) ([]byte, error) { return GoServer(sw, scan, "gen", diags) }},
+		{"validator.go", func(diags *Diagnostics) ([]byte, error) { return GoValidator(sw, scan, "gen", diags) }},
+	}
+	for _, g := range gens {
+		diags := new(Diagnostics)
+		b, err := g.gen(diags)
+		if err != nil {
+			t.Fatalf("%s: %v", g.name, err)
+		}
+		for _, d := range diags.List() {
+			t.Errorf("%s: %s", g.name, d)
+		}
+		write(filepath.Join("gen", g.name), b)
+	}
+
+	write("gen/gen_test.go", []byte(goCodeTest))
+	var cases strings.Builder
+	for _, c := range matchSegmentCases {
+		fmt.Fprintf(&cases, "\t\t{%q, %q, %#v},\n", c.template, c.seg, c.params)
+	}
+	write("gen/match_test.go", []byte(strings.Replace(goMatchTest, "\t\t// cases\n", cases.String(), 1)))
+
+	for _, args := range [][]string{{"vet", "./..."}, {"test", "./..."}} {
+		cmd := exec.Command(gobin, args...)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(), "GO111MODULE=on", "GOFLAGS=-mod=mod", "GOPROXY=off")
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("go %s: %v\n%s", args[0], err, out)
+		}
+	}
+}
+
+// goCodeTest runs requests through the generated client, validator and
+// server.
+const goCodeTest = `package gen
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"example.com/codegen/api"
+)
+
+type servers struct{}
+
+func (servers) GetUser(ctx context.Context, req *api.GetUserReq) (*GetUserResponse, error) {
+	return GetUser200(&api.User{Name: req.ID}), nil
+}
+
+func (servers) CreateUser(ctx context.Context, req *api.CreateUserReq) (*CreateUserResponse, error) {
+	return CreateUser201(&api.Created{ID: req.Name}), nil
+}
+
+func (servers) GetFile(ctx context.Context, req *api.GetFileReq) (*GetFileResponse, error) {
+	return GetFile200(&api.File{Name: req.Name}), nil
+}
+
+func TestValidate(t *testing.T) {
+	email := "bob"
+	cases := []struct {
+		req    api.CreateUserReq
+		errors string
+	}{
+		{api.CreateUserReq{Name: "bob"}, ""},
+		{api.CreateUserReq{}, "invalid request: name is required"},
+		{api.CreateUserReq{Name: "Bob"}, "invalid request: name must match ^[a-z]+$"},
+		{api.CreateUserReq{Name: "bob", Age: -1}, "invalid request: age must be at least 1"},
+		{api.CreateUserReq{Name: "bob", Email: &email}, "invalid request: email must be an email address"},
+		{api.CreateUserReq{Name: "bob", Tags: []string{"a", "b", "c", "d"}}, "invalid request: tags must have at most 3 items"},
+	}
+	for _, c := range cases {
+		err := ValidateCreateUserReq(&c.req)
+		if msg := ""; err != nil {
+			msg = err.Error()
+			if msg != c.errors {
+				t.Errorf("%+v: got %q, want %q", c.req, msg, c.errors)
+			}
+		} else if c.errors != "" {
+			t.Errorf("%+v: got no error, want %q", c.req, c.errors)
+		}
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	srv := httptest.NewServer(Middleware(NewHandler(Servers{Api: servers{}})))
+	defer srv.Close()
+	c := New(srv.URL)
+
+	user, err := c.GetUser(context.Background(), &api.GetUserReq{ID: "42"})
+	if err != nil || user.Status200 == nil || user.Status200.Name != "42" {
+		t.Fatalf("GetUser: %+v, %v", user, err)
+	}
+	file, err := c.GetFile(context.Background(), &api.GetFileReq{Name: "a.b", Limit: 2})
+	if err != nil || file.Status200 == nil || file.Status200.Name != "a.b" {
+		t.Fatalf("GetFile: %+v, %v", file, err)
+	}
+	if _, err := c.CreateUser(context.Background(), &api.CreateUserReq{Name: "Bob"}); err == nil || !strings.Contains(err.Error(), "must match") {
+		t.Fatalf("CreateUser: got %v, want a validation error", err)
+	}
+
+	for path, status := range map[string]int{
+		"/files/a.json":         http.StatusOK,
+		"/files/a.json?limit=1": http.StatusOK,
+		"/files/a.json?limit=0": http.StatusBadRequest,
+		"/files/.json":          http.StatusNotFound,
+	} {
+		res, err := http.Get(srv.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		if res.StatusCode != status {
+			t.Errorf("GET %s: got %d, want %d", path, res.StatusCode, status)
+		}
+	}
+}
+`
+
+// goMatchTest checks that the path helpers emitted for the server and the
+// validator match segments like matchSegment does.
+const goMatchTest = `package gen
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMatchSegment(t *testing.T) {
+	cases := []struct {
+		template, seg string
+		params        map[string]string
+	}{
+		// cases
+	}
+	for _, match := range []func(string, string, map[string]string) bool{serverMatchSegment, validatorMatchSegment} {
+		for _, c := range cases {
+			params := map[string]string{}
+			ok := match(c.template, c.seg, params)
+			if ok != (c.params != nil) || ok && !reflect.DeepEqual(params, c.params) {
+				t.Errorf("match(%q, %q) = %v, %v; want %v", c.template, c.seg, params, ok, c.params)
+			}
+		}
+	}
+}
+`
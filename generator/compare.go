@@ -0,0 +1,137 @@
+package generator
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-openapi/spec"
+)
+
+type Change struct {
+	Path     []string
+	Kind     byte // '+' added, '-' removed, '~' modified
+	Old, New interface{}
+}
+
+func (self Change) String() string {
+	switch self.Kind {
+	case '+':
+		return fmt.Sprintf("+ %s", joinPath(self.Path))
+	case '-':
+		return fmt.Sprintf("- %s", joinPath(self.Path))
+	}
+	return fmt.Sprintf("~ %s: %s -> %s", joinPath(self.Path), shortJSON(self.Old), shortJSON(self.New))
+}
+
+func joinPath(path []string) string {
+	parts := make([]string, len(path))
+	for i, p := range path {
+		if strings.ContainsAny(p, "./ ") {
+			p = strconv.Quote(p)
+		}
+		parts[i] = p
+	}
+	return strings.Join(parts, ".")
+}
+
+func shortJSON(v interface{}) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	if len(b) > 60 {
+		return string(b[:57]) + "..."
+	}
+	return string(b)
+}
+
+// Compare lists the differences between the JSON forms of two specs.
+func Compare(prev, next *spec.Swagger) ([]Change, error) {
+	o, err := genericJSON(prev)
+	if err != nil {
+		return nil, err
+	}
+	n, err := genericJSON(next)
+	if err != nil {
+		return nil, err
+	}
+	return compareValues(nil, o, n), nil
+}
+
+func genericJSON(v interface{}) (interface{}, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var out interface{}
+	err = dec.Decode(&out)
+	return out, err
+}
+
+func compareValues(path []string, prev, next interface{}) []Change {
+	changes := []Change{}
+	sub := func(key string) []string {
+		return append(append([]string{}, path...), key)
+	}
+
+	switch ov := prev.(type) {
+	case map[string]interface{}:
+		nv, ok := next.(map[string]interface{})
+		if !ok {
+			break
+		}
+		keys := []string{}
+		for k := range ov {
+			keys = append(keys, k)
+		}
+		for k := range nv {
+			if _, known := ov[k]; !known {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+
+		for _, k := range keys {
+			o, inOld := ov[k]
+			n, inNew := nv[k]
+			switch {
+			case !inNew:
+				changes = append(changes, Change{Path: sub(k), Kind: '-', Old: o})
+			case !inOld:
+				changes = append(changes, Change{Path: sub(k), Kind: '+', New: n})
+			default:
+				changes = append(changes, compareValues(sub(k), o, n)...)
+			}
+		}
+		return changes
+
+	case []interface{}:
+		nv, ok := next.([]interface{})
+		if !ok {
+			break
+		}
+		for i := 0; i < len(ov) || i < len(nv); i++ {
+			switch {
+			case i >= len(nv):
+				changes = append(changes, Change{Path: sub(strconv.Itoa(i)), Kind: '-', Old: ov[i]})
+			case i >= len(ov):
+				changes = append(changes, Change{Path: sub(strconv.Itoa(i)), Kind: '+', New: nv[i]})
+			default:
+				changes = append(changes, compareValues(sub(strconv.Itoa(i)), ov[i], nv[i])...)
+			}
+		}
+		return changes
+	}
+
+	if !reflect.DeepEqual(prev, next) {
+		changes = append(changes, Change{Path: path, Kind: '~', Old: prev, New: next})
+	}
+	return changes
+}
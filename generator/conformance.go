@@ -0,0 +1,90 @@
+package generator
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/go-openapi/spec"
+)
+
+// ResponseChecker checks the responses of a service against the operations
+// of its spec, matching requests to them by method and path template like
+// the mock does.
+type ResponseChecker struct {
+	checker *valueChecker
+	ops     []specOperation
+}
+
+// NewResponseChecker checks responses against the operations of sw.
+func NewResponseChecker(sw *spec.Swagger) *ResponseChecker {
+	return &ResponseChecker{checker: newValueChecker(sw), ops: specOperations(sw)}
+}
+
+// Check returns the operation answering r, its id or else its method and
+// path, and what is wrong with the response: a status the operation does
+// not declare, or a body not matching the schema declared for the status.
+// The operation is empty when none matches r.
+func (self *ResponseChecker) Check(r *http.Request, status int, body []byte) (string, []string) {
+	o := self.operation(r)
+	if o == nil {
+		return "", nil
+	}
+	name := o.op.ID
+	if name == "" {
+		name = o.method + " " + o.path
+	}
+
+	var resp *spec.Response
+	if o.op.Responses != nil {
+		if declared, ok := o.op.Responses.StatusCodeResponses[status]; ok {
+			resp = &declared
+		} else {
+			resp = o.op.Responses.Default
+		}
+	}
+	if resp == nil {
+		return name, []string{fmt.Sprintf("status %d is not declared", status)}
+	}
+	if resp.Schema == nil || r.Method == "HEAD" || status == http.StatusNoContent || status == http.StatusNotModified {
+		return name, nil
+	}
+
+	if len(strings.TrimSpace(string(body))) == 0 {
+		return name, []string{"body is empty"}
+	}
+	var v interface{}
+	if err := json.Unmarshal(body, &v); err != nil {
+		return name, []string{"body is not JSON: " + err.Error()}
+	}
+	verrs := []valueError{}
+	self.checker.check(resp.Schema, v, "body", &verrs)
+	errs := []string{}
+	for _, e := range verrs {
+		errs = append(errs, e.Field+" "+e.Message)
+	}
+	return name, errs
+}
+
+// operation is the operation answering r, the GET one for a HEAD request
+// without its own.
+func (self *ResponseChecker) operation(r *http.Request) *specOperation {
+	path := splitPath(r.URL.Path)
+	methods := []string{r.Method}
+	if r.Method == "HEAD" {
+		methods = append(methods, "GET")
+	}
+	for _, method := range methods {
+		for i := range self.ops {
+			o := &self.ops[i]
+			if o.method != method {
+				continue
+			}
+			if _, ok := matchPath(o.segs, path); ok {
+				return o
+			}
+		}
+	}
+	return nil
+}
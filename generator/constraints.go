@@ -0,0 +1,201 @@
+package generator
+
+import (
+	"fmt"
+	"go/ast"
+	"math"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/go-openapi/spec"
+)
+
+// constraints are what the validate and pattern tags of a field require,
+// e.g. `validate:"required,min=1,max=64" pattern:"^[a-z]+$"`. The validate
+// keywords are those of go-playground/validator that the spec can express,
+// the others are left to the validator.
+type constraints struct {
+	Required                   bool
+	Min, Max                   *float64
+	ExclusiveMin, ExclusiveMax bool
+	OneOf                      []string
+	Format, Pattern            string
+}
+
+// formatKeywords map validate keywords to spec formats.
+var formatKeywords = map[string]string{
+	"email":    "email",
+	"uuid":     "uuid",
+	"uri":      "uri",
+	"url":      "uri",
+	"ipv4":     "ipv4",
+	"ipv6":     "ipv6",
+	"hostname": "hostname",
+}
+
+func parseConstraints(field *ast.Field) (constraints, error) {
+	c := constraints{}
+	if field.Tag == nil {
+		return c, nil
+	}
+	tv, err := strconv.Unquote(field.Tag.Value)
+	if err != nil {
+		return c, nil
+	}
+	st := reflect.StructTag(tv)
+
+	for _, part := range strings.Split(st.Get("validate"), ",") {
+		key, value := strings.TrimSpace(part), ""
+		if i := strings.Index(key, "="); i >= 0 {
+			key, value = key[:i], key[i+1:]
+		}
+		bound := func() (*float64, error) {
+			f, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return nil, fmt.Errorf("validate tag: %s=%s is not a number", key, value)
+			}
+			return &f, nil
+		}
+		switch key {
+		case "required":
+			c.Required = true
+		case "min", "gte", "gt":
+			if c.Min, err = bound(); err != nil {
+				return c, err
+			}
+			c.ExclusiveMin = key == "gt"
+		case "max", "lte", "lt":
+			if c.Max, err = bound(); err != nil {
+				return c, err
+			}
+			c.ExclusiveMax = key == "lt"
+		case "len":
+			if c.Min, err = bound(); err != nil {
+				return c, err
+			}
+			c.Max = c.Min
+		case "oneof":
+			c.OneOf = strings.Fields(value)
+		default:
+			if format, ok := formatKeywords[key]; ok {
+				c.Format = format
+			}
+		}
+	}
+
+	if pattern, ok := st.Lookup("pattern"); ok {
+		// generated validation code uses the regexp package
+		if _, err := regexp.Compile(pattern); err != nil {
+			return c, fmt.Errorf("pattern tag: %v", err)
+		}
+		c.Pattern = pattern
+	}
+	return c, nil
+}
+
+// validations are the constraints on a value of spec type tpe, on top of
+// v: min and max bound numbers, string lengths or item counts.
+func (self constraints) validations(tpe string, v spec.CommonValidations) spec.CommonValidations {
+	length := func(f *float64, round func(float64) float64) *int64 {
+		if f == nil {
+			return nil
+		}
+		n := int64(round(*f))
+		return &n
+	}
+	switch tpe {
+	case "integer", "number":
+		if self.Min != nil {
+			v.Minimum, v.ExclusiveMinimum = self.Min, self.ExclusiveMin
+		}
+		if self.Max != nil {
+			v.Maximum, v.ExclusiveMaximum = self.Max, self.ExclusiveMax
+		}
+	case "string":
+		if self.Min != nil {
+			v.MinLength = length(self.Min, math.Ceil)
+		}
+		if self.Max != nil {
+			v.MaxLength = length(self.Max, math.Floor)
+		}
+		if self.Pattern != "" {
+			v.Pattern = self.Pattern
+		}
+	case "array":
+		if self.Min != nil {
+			v.MinItems = length(self.Min, math.Ceil)
+		}
+		if self.Max != nil {
+			v.MaxItems = length(self.Max, math.Floor)
+		}
+	}
+
+	if len(self.OneOf) > 0 {
+		enum := []interface{}{}
+		for _, s := range self.OneOf {
+			var value interface{} = s
+			switch tpe {
+			case "integer":
+				if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+					value = n
+				}
+			case "number":
+				if f, err := strconv.ParseFloat(s, 64); err == nil {
+					value = f
+				}
+			case "boolean":
+				if b, err := strconv.ParseBool(s); err == nil {
+					value = b
+				}
+			}
+			enum = append(enum, value)
+		}
+		v.Enum = enum
+	}
+	return v
+}
+
+func (self constraints) applySchema(schema *spec.Schema) {
+	tpe := ""
+	if len(schema.Type) > 0 {
+		tpe = schema.Type[0]
+	}
+	v := self.validations(tpe, schemaValidations(schema))
+	schema.Maximum, schema.ExclusiveMaximum = v.Maximum, v.ExclusiveMaximum
+	schema.Minimum, schema.ExclusiveMinimum = v.Minimum, v.ExclusiveMinimum
+	schema.MaxLength, schema.MinLength, schema.Pattern = v.MaxLength, v.MinLength, v.Pattern
+	schema.MaxItems, schema.MinItems = v.MaxItems, v.MinItems
+	schema.Enum = v.Enum
+	if self.Format != "" && tpe == "string" {
+		schema.Format = self.Format
+	}
+}
+
+func (self constraints) applyParam(param *spec.Parameter) {
+	param.CommonValidations = self.validations(param.Type, param.CommonValidations)
+	if self.Format != "" && param.Type == "string" {
+		param.Format = self.Format
+	}
+	if self.Required {
+		param.Required = true
+	}
+}
+
+func schemaValidations(schema *spec.Schema) spec.CommonValidations {
+	return spec.CommonValidations{
+		Maximum:          schema.Maximum,
+		ExclusiveMaximum: schema.ExclusiveMaximum,
+		Minimum:          schema.Minimum,
+		ExclusiveMinimum: schema.ExclusiveMinimum,
+		MaxLength:        schema.MaxLength,
+		MinLength:        schema.MinLength,
+		Pattern:          schema.Pattern,
+		MaxItems:         schema.MaxItems,
+		MinItems:         schema.MinItems,
+		UniqueItems:      schema.UniqueItems,
+		MultipleOf:       schema.MultipleOf,
+		Enum:             schema.Enum,
+	}
+}
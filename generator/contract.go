@@ -0,0 +1,620 @@
+package generator
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/format"
+	"go/token"
+	"net/url"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/go-openapi/spec"
+)
+
+// GoContract renders a Go test file of package pkg serving a request per
+// operation of the spec with the http.Handler the function named handler
+// returns, and checking that each response has a declared status and a body
+// matching the schema declared for it. Requests are built from the examples
+// of the parameters and body, or else from data synthesized like the mock
+// does. The function is left to the package, in a test file of its own,
+// with the signature func(*testing.T) http.Handler.
+func GoContract(sw *spec.Swagger, pkg, handler string, diags *Diagnostics) ([]byte, error) {
+	ops := specOperations(sw)
+	sort.SliceStable(ops, func(i, j int) bool {
+		if ops[i].path != ops[j].path {
+			return ops[i].path < ops[j].path
+		}
+		return ops[i].method < ops[j].method
+	})
+
+	cases := []contractCase{}
+	names := map[string]bool{}
+	for _, o := range ops {
+		c, err := contractRequest(sw, o)
+		if err != nil {
+			return nil, err
+		}
+		if names[c.Operation] {
+			c.Operation = o.method + " " + o.path
+		}
+		names[c.Operation] = true
+
+		if o.op.Responses != nil {
+			codes := []int{}
+			for code := range o.op.Responses.StatusCodeResponses {
+				codes = append(codes, code)
+			}
+			sort.Ints(codes)
+			for _, code := range codes {
+				resp := o.op.Responses.StatusCodeResponses[code]
+				s, err := contractSchema(resp.Schema)
+				if err != nil {
+					return nil, err
+				}
+				c.Responses = append(c.Responses, contractResponse{Status: fmt.Sprint(code), Schema: s})
+			}
+			if def := o.op.Responses.Default; def != nil {
+				s, err := contractSchema(def.Schema)
+				if err != nil {
+					return nil, err
+				}
+				c.Responses = append(c.Responses, contractResponse{Status: "default", Schema: s})
+			}
+		}
+		if len(c.Responses) == 0 {
+			diags.Warnf(token.Position{}, CodeCodegen, "operation %s declares no response, every status fails its contract test", c.Operation)
+		}
+		cases = append(cases, c)
+	}
+
+	trimmed := map[string]*checkedSchema{}
+	for name, def := range sw.Definitions {
+		def := def
+		s, err := trimSchema(&def)
+		if err != nil {
+			return nil, err
+		}
+		trimmed[name] = s
+	}
+	defs, err := json.MarshalIndent(trimmed, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	err = goContractTemplate.Execute(&buf, map[string]interface{}{
+		"Package":     pkg,
+		"Handler":     handler,
+		"Cases":       cases,
+		"Definitions": quoteLines(string(defs)),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return format.Source(buf.Bytes())
+}
+
+type contractCase struct {
+	Operation, Method, Target, Body string
+	Header                          []contractHeader
+	Responses                       []contractResponse
+}
+
+type contractHeader struct {
+	Name, Value string
+}
+
+type contractResponse struct {
+	// Status is the status code or default; Schema is the JSON of the body
+	// schema, empty for a response without a body.
+	Status, Schema string
+}
+
+// contractRequest is the request of the test case of an operation. Path
+// parameters, required parameters and those with an example are set, and
+// the body whenever there is one.
+func contractRequest(sw *spec.Swagger, o specOperation) (contractCase, error) {
+	c := contractCase{Operation: o.op.ID, Method: o.method}
+	seed := o.op.ID + " request"
+	if o.op.ID == "" {
+		c.Operation = o.method + " " + o.path
+		seed = o.method + " " + o.path + " request"
+	}
+
+	path := map[string]string{}
+	query := url.Values{}
+	form := url.Values{}
+	for _, p := range o.params {
+		if p.In == "body" {
+			v := synthesize(sw, p.Schema, seed, map[string]bool{})
+			b, err := json.Marshal(v)
+			if err != nil {
+				return c, err
+			}
+			c.Body = string(b)
+			c.Header = append(c.Header, contractHeader{Name: "Content-Type", Value: "application/json"})
+			continue
+		}
+
+		example, ok := p.Example, p.Example != nil
+		if !ok {
+			example, ok = p.Extensions["x-example"]
+		}
+		if !ok && !p.Required && p.In != "path" || p.Type == "file" {
+			continue
+		}
+		if !ok {
+			example = synthesize(sw, paramSchema(p.SimpleSchema, p.CommonValidations, p.Items), seed+" "+p.Name, map[string]bool{})
+		}
+		values := paramStrings(p.SimpleSchema, example)
+		switch p.In {
+		case "path":
+			if len(values) > 0 {
+				path[p.Name] = values[0]
+			}
+		case "query":
+			query[p.Name] = values
+		case "header":
+			if len(values) > 0 {
+				c.Header = append(c.Header, contractHeader{Name: p.Name, Value: values[0]})
+			}
+		case "formData":
+			form[p.Name] = values
+		}
+	}
+	if len(form) > 0 && c.Body == "" {
+		c.Body = form.Encode()
+		c.Header = append(c.Header, contractHeader{Name: "Content-Type", Value: "application/x-www-form-urlencoded"})
+	}
+	sort.SliceStable(c.Header, func(i, j int) bool { return c.Header[i].Name < c.Header[j].Name })
+
+	value := func(name string) string {
+		v, ok := path[name]
+		if !ok {
+			v = synthesizeString(&spec.Schema{}, name, seedNumber(seed+" "+name))
+		}
+		return url.PathEscape(v)
+	}
+	segs := make([]string, len(o.segs))
+	for i, seg := range o.segs {
+		for _, p := range segmentParams(seg) {
+			seg = strings.Replace(seg, p.Placeholder, value(p.Name), 1)
+		}
+		segs[i] = seg
+	}
+	c.Target = "/" + strings.Join(segs, "/")
+	if len(query) > 0 {
+		c.Target += "?" + query.Encode()
+	}
+	return c, nil
+}
+
+// paramStrings are the values a parameter is sent as, one per parameter
+// for arrays in multi format.
+func paramStrings(simple spec.SimpleSchema, v interface{}) []string {
+	str := func(v interface{}) string {
+		if f, ok := v.(float64); ok {
+			return formatNumber(f)
+		}
+		return fmt.Sprint(v)
+	}
+	list, ok := v.([]interface{})
+	if !ok {
+		return []string{str(v)}
+	}
+	values := []string{}
+	for _, item := range list {
+		values = append(values, str(item))
+	}
+	if simple.CollectionFormat == "multi" {
+		return values
+	}
+	sep := map[string]string{"ssv": " ", "tsv": "\t", "pipes": "|"}[simple.CollectionFormat]
+	if sep == "" {
+		sep = ","
+	}
+	return []string{strings.Join(values, sep)}
+}
+
+func contractSchema(schema *spec.Schema) (string, error) {
+	if schema == nil {
+		return "", nil
+	}
+	trimmed, err := trimSchema(schema)
+	if err != nil {
+		return "", err
+	}
+	b, err := json.Marshal(trimmed)
+	return string(b), err
+}
+
+// checkedSchema is the part of a schema contract tests check, which keeps
+// the generated file free of descriptions and extensions.
+type checkedSchema struct {
+	Ref                  string                    `json:"$ref,omitempty"`
+	Type                 string                    `json:"type,omitempty"`
+	Format               string                    `json:"format,omitempty"`
+	Pattern              string                    `json:"pattern,omitempty"`
+	Enum                 []interface{}             `json:"enum,omitempty"`
+	Required             []string                  `json:"required,omitempty"`
+	Properties           map[string]*checkedSchema `json:"properties,omitempty"`
+	AdditionalProperties json.RawMessage           `json:"additionalProperties,omitempty"`
+	Items                *checkedSchema            `json:"items,omitempty"`
+	AllOf                []*checkedSchema          `json:"allOf,omitempty"`
+	Minimum              *float64                  `json:"minimum,omitempty"`
+	Maximum              *float64                  `json:"maximum,omitempty"`
+	ExclusiveMinimum     bool                      `json:"exclusiveMinimum,omitempty"`
+	ExclusiveMaximum     bool                      `json:"exclusiveMaximum,omitempty"`
+	MultipleOf           *float64                  `json:"multipleOf,omitempty"`
+	MinLength            *int64                    `json:"minLength,omitempty"`
+	MaxLength            *int64                    `json:"maxLength,omitempty"`
+	MinItems             *int64                    `json:"minItems,omitempty"`
+	MaxItems             *int64                    `json:"maxItems,omitempty"`
+	UniqueItems          bool                      `json:"uniqueItems,omitempty"`
+	Nullable             bool                      `json:"x-nullable,omitempty"`
+}
+
+func trimSchema(schema *spec.Schema) (*checkedSchema, error) {
+	b, err := json.Marshal(schema)
+	if err != nil {
+		return nil, err
+	}
+	trimmed := new(checkedSchema)
+	if err := json.Unmarshal(b, trimmed); err != nil {
+		return nil, err
+	}
+	return trimmed, trimmed.trimAdditional()
+}
+
+// trimAdditional trims the schemas of additionalProperties, which are only
+// decoded as raw JSON since they may be booleans too.
+func (self *checkedSchema) trimAdditional() error {
+	if self == nil {
+		return nil
+	}
+	var additional *checkedSchema
+	if json.Unmarshal(self.AdditionalProperties, &additional) == nil && additional != nil {
+		if err := additional.trimAdditional(); err != nil {
+			return err
+		}
+		b, err := json.Marshal(additional)
+		if err != nil {
+			return err
+		}
+		self.AdditionalProperties = b
+	}
+	for _, prop := range self.Properties {
+		if err := prop.trimAdditional(); err != nil {
+			return err
+		}
+	}
+	for _, sub := range self.AllOf {
+		if err := sub.trimAdditional(); err != nil {
+			return err
+		}
+	}
+	return self.Items.trimAdditional()
+}
+
+var goContractTemplate = template.Must(template.New("").Parse(`// Code generated by go2swag. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net"
+	"net/http/httptest"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+// TestContract serves a request per operation with the handler returned by
+// the function below, written in a test file of the package, and checks
+// that each response has a declared status and a body matching the schema
+// declared for it:
+//
+//	func {{.Handler}}(t *testing.T) http.Handler
+func TestContract(t *testing.T) {
+	var defs map[string]*contractSchema
+	if err := json.Unmarshal([]byte(contractDefinitions), &defs); err != nil {
+		t.Fatal(err)
+	}
+	h := {{.Handler}}(t)
+	for _, c := range contractCases {
+		c := c
+		t.Run(c.operation, func(t *testing.T) {
+			r := httptest.NewRequest(c.method, c.target, strings.NewReader(c.body))
+			for _, kv := range c.header {
+				r.Header.Set(kv[0], kv[1])
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+			for _, err := range checkContract(c, defs, w) {
+				t.Errorf("%s %s: %s", c.method, c.target, err)
+			}
+		})
+	}
+}
+
+type contractCase struct {
+	operation, method, target, body string
+	header                          [][2]string
+	// responses are the JSON of the body schemas by status code or
+	// default, empty for responses without a body.
+	responses map[string]string
+}
+
+var contractCases = []contractCase{
+{{- range .Cases}}
+	{
+		operation: {{printf "%q" .Operation}},
+		method:    {{printf "%q" .Method}},
+		target:    {{printf "%q" .Target}},
+		{{- if .Body}}
+		body:      {{printf "%q" .Body}},{{end}}
+		{{- if .Header}}
+		header: [][2]string{
+		{{- range .Header}}
+			{ {{- printf "%q" .Name}}, {{printf "%q" .Value -}} },{{end}}
+		},{{end}}
+		responses: map[string]string{
+		{{- range .Responses}}
+			{{printf "%q" .Status}}: {{printf "%q" .Schema}},{{end}}
+		},
+	},
+{{- end}}
+}
+
+const contractDefinitions = {{.Definitions}}
+
+func checkContract(c contractCase, defs map[string]*contractSchema, w *httptest.ResponseRecorder) []string {
+	raw, ok := c.responses[strconv.Itoa(w.Code)]
+	if !ok {
+		raw, ok = c.responses["default"]
+	}
+	if !ok {
+		if len(c.responses) == 0 {
+			return []string{fmt.Sprintf("status %d is not declared, the operation declares no response", w.Code)}
+		}
+		declared := []string{}
+		for status := range c.responses {
+			declared = append(declared, status)
+		}
+		sort.Strings(declared)
+		return []string{fmt.Sprintf("status %d is not declared, use one of %s", w.Code, strings.Join(declared, ", "))}
+	}
+	if raw == "" || c.method == "HEAD" || w.Code == 204 {
+		return nil
+	}
+
+	var schema contractSchema
+	if err := json.Unmarshal([]byte(raw), &schema); err != nil {
+		return []string{err.Error()}
+	}
+	if w.Body.Len() == 0 {
+		return []string{fmt.Sprintf("status %d: body is empty", w.Code)}
+	}
+	var v interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &v); err != nil {
+		return []string{fmt.Sprintf("status %d: body is not JSON: %v", w.Code, err)}
+	}
+	errs := []string{}
+	checkContractSchema(defs, &schema, v, "body", &errs, map[string]bool{})
+	for i := range errs {
+		errs[i] = fmt.Sprintf("status %d: %s", w.Code, errs[i])
+	}
+	return errs
+}
+
+// contractSchema is the part of a schema the contract tests check.
+type contractSchema struct {
+	Ref                  string                     ` + "`json:\"$ref\"`" + `
+	Type                 string                     ` + "`json:\"type\"`" + `
+	Format               string                     ` + "`json:\"format\"`" + `
+	Pattern              string                     ` + "`json:\"pattern\"`" + `
+	Enum                 []interface{}              ` + "`json:\"enum\"`" + `
+	Required             []string                   ` + "`json:\"required\"`" + `
+	Properties           map[string]*contractSchema ` + "`json:\"properties\"`" + `
+	AdditionalProperties json.RawMessage            ` + "`json:\"additionalProperties\"`" + `
+	Items                *contractSchema            ` + "`json:\"items\"`" + `
+	AllOf                []*contractSchema          ` + "`json:\"allOf\"`" + `
+	Minimum              *float64                   ` + "`json:\"minimum\"`" + `
+	Maximum              *float64                   ` + "`json:\"maximum\"`" + `
+	ExclusiveMinimum     bool                       ` + "`json:\"exclusiveMinimum\"`" + `
+	ExclusiveMaximum     bool                       ` + "`json:\"exclusiveMaximum\"`" + `
+	MultipleOf           *float64                   ` + "`json:\"multipleOf\"`" + `
+	MinLength            *int                       ` + "`json:\"minLength\"`" + `
+	MaxLength            *int                       ` + "`json:\"maxLength\"`" + `
+	MinItems             *int                       ` + "`json:\"minItems\"`" + `
+	MaxItems             *int                       ` + "`json:\"maxItems\"`" + `
+	UniqueItems          bool                       ` + "`json:\"uniqueItems\"`" + `
+	Nullable             bool                       ` + "`json:\"x-nullable\"`" + `
+}
+
+// checkContractSchema appends what is wrong with v to errs. A null stands for a nil
+// slice, map or pointer, so it is accepted for arrays, objects and
+// x-nullable schemas. seen holds the definitions already resolved for v, to
+// stop at reference cycles.
+func checkContractSchema(defs map[string]*contractSchema, s *contractSchema, v interface{}, path string, errs *[]string, seen map[string]bool) {
+	fail := func(format string, args ...interface{}) {
+		*errs = append(*errs, path+" "+fmt.Sprintf(format, args...))
+	}
+
+	for s != nil && s.Ref != "" {
+		name := strings.TrimPrefix(s.Ref, "#/definitions/")
+		if seen[name] {
+			return
+		}
+		seen[name] = true
+		defer delete(seen, name)
+		s = defs[name]
+	}
+	if s == nil {
+		return
+	}
+	for _, sub := range s.AllOf {
+		checkContractSchema(defs, sub, v, path, errs, seen)
+	}
+	if v == nil {
+		if !s.Nullable && s.Type != "" && s.Type != "array" && s.Type != "object" {
+			fail("must not be null")
+		}
+		return
+	}
+
+	ok := true
+	switch s.Type {
+	case "object":
+		_, ok = v.(map[string]interface{})
+	case "array":
+		_, ok = v.([]interface{})
+	case "string":
+		_, ok = v.(string)
+	case "integer":
+		f, isNumber := v.(float64)
+		ok = isNumber && f == math.Trunc(f)
+	case "number":
+		_, ok = v.(float64)
+	case "boolean":
+		_, ok = v.(bool)
+	}
+	if !ok {
+		fail("must be of type %s", s.Type)
+		return
+	}
+
+	if len(s.Enum) > 0 {
+		b, _ := json.Marshal(v)
+		found, values := false, []string{}
+		for _, e := range s.Enum {
+			eb, _ := json.Marshal(e)
+			found = found || string(eb) == string(b)
+			values = append(values, fmt.Sprint(e))
+		}
+		if !found {
+			fail("must be one of %s", strings.Join(values, ", "))
+		}
+	}
+
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for _, name := range s.Required {
+			if _, ok := v[name]; !ok {
+				*errs = append(*errs, path+"."+name+" is required")
+			}
+		}
+		var additional *contractSchema
+		if len(s.AdditionalProperties) > 0 && s.AdditionalProperties[0] == '{' {
+			json.Unmarshal(s.AdditionalProperties, &additional)
+		}
+		names := []string{}
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if prop, ok := s.Properties[name]; ok {
+				checkContractSchema(defs, prop, v[name], path+"."+name, errs, map[string]bool{})
+			} else if additional != nil {
+				checkContractSchema(defs, additional, v[name], path+"."+name, errs, map[string]bool{})
+			}
+		}
+	case []interface{}:
+		if s.MinItems != nil && len(v) < *s.MinItems {
+			fail("must have at least %d items", *s.MinItems)
+		}
+		if s.MaxItems != nil && len(v) > *s.MaxItems {
+			fail("must have at most %d items", *s.MaxItems)
+		}
+		if s.UniqueItems {
+			items := map[string]bool{}
+			for _, item := range v {
+				b, _ := json.Marshal(item)
+				if items[string(b)] {
+					fail("must not contain duplicates")
+					break
+				}
+				items[string(b)] = true
+			}
+		}
+		for i, item := range v {
+			checkContractSchema(defs, s.Items, item, path+"["+strconv.Itoa(i)+"]", errs, map[string]bool{})
+		}
+	case string:
+		n := utf8.RuneCountInString(v)
+		if s.MinLength != nil && n < *s.MinLength {
+			fail("must be at least %d characters long", *s.MinLength)
+		}
+		if s.MaxLength != nil && n > *s.MaxLength {
+			fail("must be at most %d characters long", *s.MaxLength)
+		}
+		if s.Pattern != "" {
+			if rx, err := regexp.Compile(s.Pattern); err == nil && !rx.MatchString(v) {
+				fail("must match %s", s.Pattern)
+			}
+		}
+		if !contractFormat(s.Format, v) {
+			fail("must be of format %s", s.Format)
+		}
+	case float64:
+		if s.Minimum != nil && s.ExclusiveMinimum && v <= *s.Minimum {
+			fail("must be greater than %v", *s.Minimum)
+		} else if s.Minimum != nil && v < *s.Minimum {
+			fail("must be at least %v", *s.Minimum)
+		}
+		if s.Maximum != nil && s.ExclusiveMaximum && v >= *s.Maximum {
+			fail("must be less than %v", *s.Maximum)
+		} else if s.Maximum != nil && v > *s.Maximum {
+			fail("must be at most %v", *s.Maximum)
+		}
+		if s.MultipleOf != nil && *s.MultipleOf != 0 && math.Mod(v, *s.MultipleOf) != 0 {
+			fail("must be a multiple of %v", *s.MultipleOf)
+		}
+	}
+}
+
+var (
+	contractHostname = regexp.MustCompile(` + "`" + `^[a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?(\.[a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)*$` + "`" + `)
+	contractUUID     = regexp.MustCompile(` + "`" + `^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$` + "`" + `)
+)
+
+// contractFormat checks the formats generated validation code checks;
+// other formats are not checked.
+func contractFormat(format, s string) bool {
+	switch format {
+	case "date":
+		_, err := time.Parse("2006-01-02", s)
+		return err == nil
+	case "date-time":
+		_, err := time.Parse(time.RFC3339, s)
+		return err == nil
+	case "email":
+		a, err := mail.ParseAddress(s)
+		return err == nil && a.Address == s
+	case "hostname":
+		return len(s) <= 253 && contractHostname.MatchString(s)
+	case "ipv4":
+		return net.ParseIP(s) != nil && !strings.Contains(s, ":")
+	case "ipv6":
+		return net.ParseIP(s) != nil && strings.Contains(s, ":")
+	case "uri":
+		u, err := url.Parse(s)
+		return err == nil && u.IsAbs()
+	case "uuid":
+		return contractUUID.MatchString(s)
+	}
+	return true
+}
+`))
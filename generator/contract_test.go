@@ -0,0 +1,194 @@
+package generator
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/go-openapi/spec"
+)
+
+const contractSpec = `{
+  "swagger": "2.0",
+  "info": {"title": "contract", "version": "1"},
+  "paths": {
+    "/items/{id}/files/{name}.json": {
+      "get": {
+        "operationId": "getFile",
+        "parameters": [
+          {"name": "id", "in": "path", "type": "integer", "required": true, "x-example": 7},
+          {"name": "name", "in": "path", "type": "string", "required": true},
+          {"name": "q", "in": "query", "type": "string", "required": true, "x-example": "a b"},
+          {"name": "X-Key", "in": "header", "type": "string", "required": true, "x-example": "k"},
+          {"name": "skip", "in": "query", "type": "integer"}
+        ],
+        "responses": {"200": {"description": "ok", "schema": {"$ref": "#/definitions/File"}}}
+      }
+    },
+    "/a": {"get": {"operationId": "dup", "responses": {"200": {"description": "ok", "schema": {"type": "string"}}}}},
+    "/b": {"get": {"operationId": "dup", "responses": {"200": {"description": "ok"}}}},
+    "/forms": {
+      "post": {
+        "operationId": "submitForm",
+        "consumes": ["application/x-www-form-urlencoded"],
+        "parameters": [
+          {"name": "title", "in": "formData", "type": "string", "required": true, "x-example": "Hello there"},
+          {"name": "count", "in": "formData", "type": "integer", "required": true, "x-example": 3}
+        ],
+        "responses": {"204": {"description": "done"}}
+      }
+    },
+    "/ping": {"get": {"operationId": "ping"}},
+    "/tree": {"get": {"operationId": "getTree", "responses": {"200": {"description": "ok", "schema": {"$ref": "#/definitions/Node"}}}}}
+  },
+  "definitions": {
+    "File": {"type": "object", "required": ["name"], "properties": {"name": {"type": "string"}}},
+    "Node": {"type": "object", "required": ["id"], "properties": {"id": {"type": "integer"}, "next": {"$ref": "#/definitions/Node"}}}
+  }
+}`
+
+func testContractSpec(t *testing.T) *spec.Swagger {
+	t.Helper()
+	sw := new(spec.Swagger)
+	if err := json.Unmarshal([]byte(contractSpec), sw); err != nil {
+		t.Fatal(err)
+	}
+	return sw
+}
+
+func TestContractRequests(t *testing.T) {
+	sw := testContractSpec(t)
+	got := map[string]contractCase{}
+	for _, o := range specOperations(sw) {
+		c, err := contractRequest(sw, o)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got[c.Operation] = c
+	}
+
+	want := map[string]contractCase{
+		"getFile": {
+			Operation: "getFile", Method: "GET", Target: "/items/7/files/name-951.json?q=a+b",
+			Header: []contractHeader{{"X-Key", "k"}},
+		},
+		"submitForm": {
+			Operation: "submitForm", Method: "POST", Target: "/forms", Body: "count=3&title=Hello+there",
+			Header: []contractHeader{{"Content-Type", "application/x-www-form-urlencoded"}},
+		},
+	}
+	for name, w := range want {
+		if !reflect.DeepEqual(got[name], w) {
+			t.Errorf("%s: got %+v, want %+v", name, got[name], w)
+		}
+	}
+}
+
+func TestGoContract(t *testing.T) {
+	gobin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go command not found")
+	}
+
+	diags := new(Diagnostics)
+	src, err := GoContract(testContractSpec(t), "svc", "newContractHandler", diags)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gotDiags := []string{}
+	for _, d := range diags.List() {
+		gotDiags = append(gotDiags, d.String())
+	}
+	wantDiags := []string{"go2swag: warning: operation ping declares no response, every status fails its contract test [GS401]"}
+	if !reflect.DeepEqual(gotDiags, wantDiags) {
+		t.Errorf("got diagnostics %q, want %q", gotDiags, wantDiags)
+	}
+
+	dir, err := ioutil.TempDir("", "go2swag-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for name, b := range map[string]string{
+		"go.mod":           "module example.com/svc\n\ngo 1.13\n",
+		"svc.go":           "package svc\n",
+		"contract_test.go": string(src),
+		"handler_test.go":  contractHandler,
+	} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(b), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	env := append(os.Environ(), "GO111MODULE=on", "GOFLAGS=-mod=mod", "GOPROXY=off")
+	vet := exec.Command(gobin, "vet", "./...")
+	vet.Dir, vet.Env = dir, env
+	if out, err := vet.CombinedOutput(); err != nil {
+		t.Fatalf("go vet: %v\n%s", err, out)
+	}
+
+	// ping declares no response, so it fails whatever the handler does;
+	// the tree the handler answers is wrong below its first level
+	test := exec.Command(gobin, "test", "-v", "-run", "TestContract", "./...")
+	test.Dir, test.Env = dir, env
+	out, err := test.CombinedOutput()
+	if err == nil {
+		t.Fatalf("go test passed, want ping to fail\n%s", out)
+	}
+	for _, line := range []string{
+		"--- PASS: TestContract/GET_/b",
+		"--- PASS: TestContract/dup",
+		"--- PASS: TestContract/submitForm",
+		"--- PASS: TestContract/getFile",
+		"--- FAIL: TestContract/ping",
+		"GET /ping: status 200 is not declared, the operation declares no response",
+		"--- FAIL: TestContract/getTree",
+		"GET /tree: status 200: body.next.id must be of type integer",
+	} {
+		if !strings.Contains(string(out), line) {
+			t.Errorf("go test output lacks %q\n%s", line, out)
+		}
+	}
+}
+
+// contractHandler answers as declared only the requests built as expected.
+const contractHandler = `package svc
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"testing"
+)
+
+var filePath = regexp.MustCompile("^/items/7/files/[a-z]+-[0-9]+\\.json$")
+
+func newContractHandler(t *testing.T) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/a":
+			fmt.Fprint(w, ` + "`" + `"ok"` + "`" + `)
+		case "/b", "/ping":
+		case "/tree":
+			fmt.Fprint(w, ` + "`" + `{"id": 1, "next": {"id": "2"}}` + "`" + `)
+		case "/forms":
+			if r.Method != "POST" || r.FormValue("title") != "Hello there" || r.FormValue("count") != "3" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			if !filePath.MatchString(r.URL.Path) || r.URL.Query().Get("q") != "a b" || r.Header.Get("X-Key") != "k" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			fmt.Fprint(w, ` + "`" + `{"name": "report"}` + "`" + `)
+		}
+	})
+}
+`
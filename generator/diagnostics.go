@@ -0,0 +1,310 @@
+package generator
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/token"
+	"io"
+	"strconv"
+	"strings"
+)
+
+type Severity int
+
+const (
+	SeverityWarning Severity = iota
+	SeverityError
+)
+
+func (self Severity) String() string {
+	if self == SeverityError {
+		return "error"
+	}
+	return "warning"
+}
+
+// Diagnostic codes are part of the output contract, keep them stable.
+const (
+	CodeLoad            = "GS001"
+	CodePackage         = "GS002"
+	CodeInput           = "GS003"
+	CodeOutput          = "GS004"
+	CodeUnknownAnno     = "GS101"
+	CodeMalformedAnno   = "GS102"
+	CodeDetachedAnno    = "GS103"
+	CodeBadStatusCode   = "GS104"
+	CodeUnknownMethod   = "GS201"
+	CodeUnknownRoute    = "GS202"
+	CodeUnsupportedType = "GS203"
+	CodeDuplicateRoute  = "GS204"
+	CodeRouteConflict   = "GS205"
+	CodeAmbiguousPath   = "GS206"
+	CodeTrailingSlash   = "GS207"
+	CodeBadConstraint   = "GS208"
+	CodeGroupConflict   = "GS209"
+	CodeMergeConflict   = "GS301"
+	CodeInputConflict   = "GS302"
+	CodeOverlay         = "GS303"
+	CodePlugin          = "GS304"
+	CodeCodegen         = "GS401"
+)
+
+var codeDescriptions = map[string]string{
+	CodeLoad:            "packages could not be loaded",
+	CodePackage:         "package contains errors",
+	CodeInput:           "input spec could not be read",
+	CodeOutput:          "output could not be written",
+	CodeUnknownAnno:     "unknown swag annotation",
+	CodeMalformedAnno:   "malformed swag annotation",
+	CodeDetachedAnno:    "annotation is not attached to a type declaration",
+	CodeBadStatusCode:   "invalid HTTP status code",
+	CodeUnknownMethod:   "unknown HTTP method",
+	CodeUnknownRoute:    "annotation refers to an unknown route",
+	CodeUnsupportedType: "type cannot be represented in the spec",
+	CodeDuplicateRoute:  "operation id is declared more than once",
+	CodeRouteConflict:   "path and method are already bound to another operation",
+	CodeAmbiguousPath:   "templated paths cannot be told apart",
+	CodeTrailingSlash:   "paths differ only by a trailing slash",
+	CodeBadConstraint:   "validate or pattern tag cannot be used",
+	CodeGroupConflict:   "package declares differing swag:group annotations",
+	CodeMergeConflict:   "hand edits of generated content conflict with source changes",
+	CodeInputConflict:   "input specs disagree",
+	CodeOverlay:         "overlay could not be applied",
+	CodePlugin:          "plugin failed or returned an invalid result",
+	CodeCodegen:         "generated code cannot use a route or type",
+}
+
+type Diagnostic struct {
+	Pos      token.Position
+	Severity Severity
+	Code     string
+	Message  string
+	Related  []token.Position
+}
+
+func (self *Diagnostic) String() string {
+	pos := self.Pos.String()
+	if pos == "-" {
+		pos = "go2swag"
+	}
+	return fmt.Sprintf("%s: %s: %s [%s]", pos, self.Severity, self.Message, self.Code)
+}
+
+type Diagnostics struct {
+	list []*Diagnostic
+}
+
+// List returns the diagnostics in the order they were reported.
+func (self *Diagnostics) List() []*Diagnostic {
+	return self.list
+}
+
+// Append adds diagnostics reported elsewhere, e.g. by another Generate call.
+func (self *Diagnostics) Append(list ...*Diagnostic) {
+	self.list = append(self.list, list...)
+}
+
+func (self *Diagnostics) add(pos token.Position, sev Severity, code, format string, args ...interface{}) *Diagnostic {
+	d := &Diagnostic{
+		Pos:      pos,
+		Severity: sev,
+		Code:     code,
+		Message:  fmt.Sprintf(format, args...),
+	}
+	self.list = append(self.list, d)
+	return d
+}
+
+func (self *Diagnostics) Errorf(pos token.Position, code, format string, args ...interface{}) *Diagnostic {
+	return self.add(pos, SeverityError, code, format, args...)
+}
+
+func (self *Diagnostics) Warnf(pos token.Position, code, format string, args ...interface{}) *Diagnostic {
+	return self.add(pos, SeverityWarning, code, format, args...)
+}
+
+// Relate attaches further source locations, e.g. the first definition of a duplicate.
+func (self *Diagnostic) Relate(pos ...token.Position) *Diagnostic {
+	for _, p := range pos {
+		if p.Filename != "" || p.IsValid() {
+			self.Related = append(self.Related, p)
+		}
+	}
+	return self
+}
+
+func (self *Diagnostics) Count(sev Severity) int {
+	n := 0
+	for _, d := range self.list {
+		if d.Severity == sev {
+			n++
+		}
+	}
+	return n
+}
+
+// Failed reports whether the run should exit non-zero; strict also fails on warnings.
+func (self *Diagnostics) Failed(strict bool) bool {
+	if self.Count(SeverityError) > 0 {
+		return true
+	}
+	return strict && self.Count(SeverityWarning) > 0
+}
+
+func (self *Diagnostics) Render(w io.Writer, format string) error {
+	switch strings.ToLower(format) {
+	case "", "text":
+		for _, d := range self.list {
+			if _, err := fmt.Fprintln(w, d.String()); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "json":
+		return self.renderJSON(w)
+	case "sarif":
+		return self.renderSARIF(w)
+	}
+	return fmt.Errorf("unknown diagnostics format %q", format)
+}
+
+type jsonPosition struct {
+	File   string `json:"file,omitempty"`
+	Line   int    `json:"line,omitempty"`
+	Column int    `json:"column,omitempty"`
+}
+
+type jsonDiagnostic struct {
+	jsonPosition
+	Severity string         `json:"severity"`
+	Code     string         `json:"code"`
+	Message  string         `json:"message"`
+	Related  []jsonPosition `json:"related,omitempty"`
+}
+
+func (self *Diagnostics) renderJSON(w io.Writer) error {
+	out := []jsonDiagnostic{}
+	for _, d := range self.list {
+		jd := jsonDiagnostic{
+			jsonPosition: jsonPosition{File: d.Pos.Filename, Line: d.Pos.Line, Column: d.Pos.Column},
+			Severity:     d.Severity.String(),
+			Code:         d.Code,
+			Message:      d.Message,
+		}
+		for _, p := range d.Related {
+			jd.Related = append(jd.Related, jsonPosition{File: p.Filename, Line: p.Line, Column: p.Column})
+		}
+		out = append(out, jd)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
+
+func (self *Diagnostics) renderSARIF(w io.Writer) error {
+	type message struct {
+		Text string `json:"text"`
+	}
+	type region struct {
+		StartLine   int `json:"startLine,omitempty"`
+		StartColumn int `json:"startColumn,omitempty"`
+	}
+	type artifactLocation struct {
+		URI string `json:"uri"`
+	}
+	type physicalLocation struct {
+		ArtifactLocation artifactLocation `json:"artifactLocation"`
+		Region           *region          `json:"region,omitempty"`
+	}
+	type location struct {
+		PhysicalLocation physicalLocation `json:"physicalLocation"`
+	}
+	type result struct {
+		RuleID           string     `json:"ruleId"`
+		Level            string     `json:"level"`
+		Message          message    `json:"message"`
+		Locations        []location `json:"locations,omitempty"`
+		RelatedLocations []location `json:"relatedLocations,omitempty"`
+	}
+	type rule struct {
+		ID               string  `json:"id"`
+		ShortDescription message `json:"shortDescription"`
+	}
+	type driver struct {
+		Name  string `json:"name"`
+		Rules []rule `json:"rules"`
+	}
+	type tool struct {
+		Driver driver `json:"driver"`
+	}
+	type run struct {
+		Tool    tool     `json:"tool"`
+		Results []result `json:"results"`
+	}
+	type log struct {
+		Schema  string `json:"$schema"`
+		Version string `json:"version"`
+		Runs    []run  `json:"runs"`
+	}
+
+	r := run{Tool: tool{Driver: driver{Name: "go2swag", Rules: []rule{}}}, Results: []result{}}
+	seen := map[string]bool{}
+	for _, d := range self.list {
+		if !seen[d.Code] {
+			seen[d.Code] = true
+			r.Tool.Driver.Rules = append(r.Tool.Driver.Rules, rule{ID: d.Code, ShortDescription: message{Text: codeDescriptions[d.Code]}})
+		}
+
+		toLocation := func(pos token.Position) location {
+			loc := location{PhysicalLocation: physicalLocation{ArtifactLocation: artifactLocation{URI: pos.Filename}}}
+			if pos.Line > 0 {
+				loc.PhysicalLocation.Region = &region{StartLine: pos.Line, StartColumn: pos.Column}
+			}
+			return loc
+		}
+
+		res := result{RuleID: d.Code, Level: d.Severity.String(), Message: message{Text: d.Message}}
+		if d.Pos.Filename != "" {
+			res.Locations = append(res.Locations, toLocation(d.Pos))
+		}
+		for _, p := range d.Related {
+			res.RelatedLocations = append(res.RelatedLocations, toLocation(p))
+		}
+		r.Results = append(r.Results, res)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	return enc.Encode(log{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs:    []run{r},
+	})
+}
+
+// parsePosition turns the "file:line:col" form used by go/packages errors into a position.
+func parsePosition(s string) token.Position {
+	pos := token.Position{Filename: s}
+	parts := strings.Split(s, ":")
+	n := len(parts)
+	if n >= 3 {
+		line, lerr := strconv.Atoi(parts[n-2])
+		col, cerr := strconv.Atoi(parts[n-1])
+		if lerr == nil && cerr == nil {
+			pos.Filename = strings.Join(parts[:n-2], ":")
+			pos.Line, pos.Column = line, col
+		}
+	}
+	// file:line, where the file may hold a colon too, e.g. C:\src\a.go:12
+	if pos.Line == 0 && n >= 2 {
+		if line, err := strconv.Atoi(parts[n-1]); err == nil {
+			pos.Filename, pos.Line = strings.Join(parts[:n-1], ":"), line
+		}
+	}
+	if pos.Filename == "-" {
+		pos.Filename = ""
+	}
+	return pos
+}
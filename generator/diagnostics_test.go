@@ -0,0 +1,151 @@
+package generator
+
+import (
+	"bytes"
+	"encoding/json"
+	"go/token"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestParsePosition(t *testing.T) {
+	cases := []struct {
+		in   string
+		want token.Position
+	}{
+		{"a.go:12:3", token.Position{Filename: "a.go", Line: 12, Column: 3}},
+		{"a.go:12", token.Position{Filename: "a.go", Line: 12}},
+		{"a.go", token.Position{Filename: "a.go"}},
+		{"-", token.Position{}},
+		{"", token.Position{}},
+		{`C:\src\a.go:12:3`, token.Position{Filename: `C:\src\a.go`, Line: 12, Column: 3}},
+		{`C:\src\a.go:12`, token.Position{Filename: `C:\src\a.go`, Line: 12}},
+		{`C:\src\a.go`, token.Position{Filename: `C:\src\a.go`}},
+		{"a.go:x:3", token.Position{Filename: "a.go:x", Line: 3}},
+		{"pkg: no Go files", token.Position{Filename: "pkg: no Go files"}},
+	}
+	for _, c := range cases {
+		if got := parsePosition(c.in); got != c.want {
+			t.Errorf("parsePosition(%q) = %#v, want %#v", c.in, got, c.want)
+		}
+	}
+}
+
+func TestRenderSARIF(t *testing.T) {
+	diags := new(Diagnostics)
+	diags.Errorf(token.Position{Filename: "api.go", Line: 3, Column: 1}, CodeDuplicateRoute, "operation getUser is declared twice").
+		Relate(token.Position{Filename: "other.go", Line: 7, Column: 1})
+	diags.Warnf(token.Position{Filename: "api.go", Line: 9}, CodeDuplicateRoute, "again")
+	diags.Warnf(token.Position{}, CodeLoad, "no position")
+
+	var buf bytes.Buffer
+	if err := diags.Render(&buf, "SARIF"); err != nil {
+		t.Fatal(err)
+	}
+	var got struct {
+		Version string
+		Runs    []struct {
+			Tool struct {
+				Driver struct {
+					Name  string
+					Rules []struct {
+						ID               string
+						ShortDescription struct{ Text string }
+					}
+				}
+			}
+			Results []struct {
+				RuleID           string
+				Level            string
+				Message          struct{ Text string }
+				Locations        []sarifLocation
+				RelatedLocations []sarifLocation
+			}
+		}
+	}
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+
+	if got.Version != "2.1.0" || len(got.Runs) != 1 {
+		t.Fatalf("got version %q and %d runs", got.Version, len(got.Runs))
+	}
+	run := got.Runs[0]
+	rules := []string{}
+	for _, r := range run.Tool.Driver.Rules {
+		rules = append(rules, r.ID+" "+r.ShortDescription.Text)
+	}
+	if want := []string{CodeDuplicateRoute + " " + codeDescriptions[CodeDuplicateRoute], CodeLoad + " " + codeDescriptions[CodeLoad]}; !reflect.DeepEqual(rules, want) {
+		t.Errorf("got rules %q, want %q", rules, want)
+	}
+
+	results := []string{}
+	for _, r := range run.Results {
+		s := r.RuleID + " " + r.Level + " " + r.Message.Text
+		for _, l := range r.Locations {
+			s += " at " + l.String()
+		}
+		for _, l := range r.RelatedLocations {
+			s += " related " + l.String()
+		}
+		results = append(results, s)
+	}
+	want := []string{
+		"GS204 error operation getUser is declared twice at api.go:3:1 related other.go:7:1",
+		"GS204 warning again at api.go:9:0",
+		"GS001 warning no position",
+	}
+	if !reflect.DeepEqual(results, want) {
+		t.Errorf("got results\n%s\nwant\n%s", strings.Join(results, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestRenderJSON(t *testing.T) {
+	diags := new(Diagnostics)
+	diags.Errorf(token.Position{Filename: "api.go", Line: 3, Column: 1}, CodeDuplicateRoute, "declared twice").
+		Relate(token.Position{Filename: "other.go", Line: 7})
+	diags.Warnf(token.Position{}, CodeLoad, "no position")
+
+	var buf bytes.Buffer
+	if err := diags.Render(&buf, "json"); err != nil {
+		t.Fatal(err)
+	}
+	var got []map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	want := []map[string]interface{}{
+		{
+			"file": "api.go", "line": 3.0, "column": 1.0, "severity": "error", "code": "GS204", "message": "declared twice",
+			"related": []interface{}{map[string]interface{}{"file": "other.go", "line": 7.0}},
+		},
+		{"severity": "warning", "code": "GS001", "message": "no position"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %s", buf.Bytes())
+	}
+}
+
+func TestRenderUnknownFormat(t *testing.T) {
+	err := new(Diagnostics).Render(new(bytes.Buffer), "xml")
+	if err == nil || err.Error() != `unknown diagnostics format "xml"` {
+		t.Errorf("got %v", err)
+	}
+}
+
+type sarifLocation struct {
+	PhysicalLocation struct {
+		ArtifactLocation struct{ URI string }
+		Region           *struct{ StartLine, StartColumn int }
+	}
+}
+
+func (self sarifLocation) String() string {
+	s := self.PhysicalLocation.ArtifactLocation.URI
+	if r := self.PhysicalLocation.Region; r != nil {
+		s += ":" + strconv.Itoa(r.StartLine) + ":" + strconv.Itoa(r.StartColumn)
+	}
+	return s
+}
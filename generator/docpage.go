@@ -0,0 +1,260 @@
+package generator
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// DocPageConfig tells the documentation page where to find things. URLs
+// are relative to the page. Without Events the page does not live-reload;
+// without Proxy try-it-out requests go straight to the API. SpecData
+// inlines the spec instead, for a page that stands on its own and so has
+// no try-it-out either.
+type DocPageConfig struct {
+	Spec     string          `json:"spec,omitempty"`
+	SpecData json.RawMessage `json:"specData,omitempty"`
+	Events   string          `json:"events,omitempty"`
+	Proxy    string          `json:"proxy,omitempty"`
+}
+
+// DocPage is a self-contained HTML page rendering the spec, with no
+// external requests so it works offline. It is a small renderer of its own,
+// not Swagger UI or Redoc, and covers operations, parameters, schemas and
+// try-it-out only.
+func DocPage(cfg DocPageConfig) string {
+	b, _ := json.Marshal(cfg)
+	return strings.Replace(docPage, "/*CONFIG*/{}", string(b), 1)
+}
+
+const docPage = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>API documentation</title>
+<style>
+body { font: 14px/1.45 -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; margin: 0; color: #1f2328; background: #f6f8fa; }
+header { background: #24292f; color: #fff; padding: 16px 32px; }
+header h1 { margin: 0; font-size: 22px; }
+header p { margin: 4px 0 0; opacity: .8; white-space: pre-line; }
+main { max-width: 1100px; margin: 0 auto; padding: 16px 32px 64px; }
+h2 { border-bottom: 1px solid #d0d7de; padding-bottom: 4px; margin-top: 32px; }
+.op { background: #fff; border: 1px solid #d0d7de; border-radius: 6px; margin: 8px 0; }
+.op > .head { display: flex; gap: 12px; align-items: center; padding: 8px 12px; cursor: pointer; }
+.method { font-weight: 700; min-width: 64px; text-align: center; border-radius: 4px; color: #fff; padding: 2px 6px; text-transform: uppercase; }
+.get { background: #0969da; } .post { background: #1a7f37; } .put { background: #9a6700; }
+.delete { background: #cf222e; } .patch { background: #8250df; } .head, .options { background: #57606a; }
+.path { font-family: ui-monospace, Menlo, monospace; font-weight: 600; }
+.summary { color: #57606a; }
+.body { display: none; border-top: 1px solid #d0d7de; padding: 12px; }
+.op.open > .body { display: block; }
+table { border-collapse: collapse; width: 100%; margin: 8px 0; }
+th, td { text-align: left; border-bottom: 1px solid #eaeef2; padding: 4px 8px; vertical-align: top; }
+pre { background: #f6f8fa; border: 1px solid #eaeef2; border-radius: 4px; padding: 8px; overflow: auto; margin: 4px 0; }
+input, textarea { font: 13px ui-monospace, Menlo, monospace; width: 100%; box-sizing: border-box; }
+textarea { min-height: 100px; }
+button { margin-top: 8px; padding: 4px 12px; }
+.muted { color: #57606a; }
+#status { position: fixed; right: 12px; bottom: 12px; background: #24292f; color: #fff; padding: 4px 10px; border-radius: 4px; display: none; }
+</style>
+</head>
+<body>
+<header><h1 id="title">API documentation</h1><p id="description"></p></header>
+<main id="content"><p class="muted">Loading spec...</p></main>
+<div id="status"></div>
+<script>
+(function () {
+  var config = /*CONFIG*/{};
+  var spec = null;
+  var open = {};
+
+  function el(tag, attrs, children) {
+    var e = document.createElement(tag);
+    for (var k in attrs || {}) {
+      if (k === "text") e.textContent = attrs[k];
+      else if (k === "onclick") e.onclick = attrs[k];
+      else e.setAttribute(k, attrs[k]);
+    }
+    (children || []).forEach(function (c) { if (c) e.appendChild(c); });
+    return e;
+  }
+
+  function resolve(schema, seen) {
+    seen = seen || {};
+    if (!schema) return schema;
+    if (schema.$ref) {
+      var name = schema.$ref.replace("#/definitions/", "");
+      if (seen[name]) return { type: "object", description: "(recursive " + name + ")" };
+      seen[name] = true;
+      return resolve((spec.definitions || {})[name], seen);
+    }
+    return schema;
+  }
+
+  function example(schema, seen) {
+    schema = resolve(schema, seen);
+    if (!schema) return null;
+    if (schema.example !== undefined) return schema.example;
+    if (schema.enum && schema.enum.length) return schema.enum[0];
+    var t = Array.isArray(schema.type) ? schema.type[0] : schema.type;
+    if (t === "object" || schema.properties) {
+      var out = {};
+      Object.keys(schema.properties || {}).forEach(function (k) { out[k] = example(schema.properties[k], seen); });
+      return out;
+    }
+    if (t === "array") return [example(schema.items, seen)];
+    if (t === "integer" || t === "number") return 0;
+    if (t === "boolean") return false;
+    if (t === "string") return schema.format === "date-time" ? new Date(0).toISOString() : "string";
+    return null;
+  }
+
+  function schemaText(schema) {
+    return JSON.stringify(example(schema, {}), null, 2);
+  }
+
+  function operation(path, method, op) {
+    var key = method + " " + path;
+    var box = el("div", { "class": "op" + (open[key] ? " open" : "") });
+    var head = el("div", { "class": "head", onclick: function () {
+      open[key] = !open[key];
+      box.className = "op" + (open[key] ? " open" : "");
+    } }, [
+      el("span", { "class": "method " + method, text: method }),
+      el("span", { "class": "path", text: path }),
+      el("span", { "class": "summary", text: op.summary || "" })
+    ]);
+    var body = el("div", { "class": "body" });
+    if (op.description) body.appendChild(el("p", { text: op.description }));
+
+    var params = (op.parameters || []);
+    var inputs = {};
+    if (params.length) {
+      var rows = [el("tr", {}, [el("th", { text: "Name" }), el("th", { text: "In" }), el("th", { text: "Type" }), el("th", { text: "Description" }), config.specData ? null : el("th", { text: "Value" })])];
+      params.forEach(function (p) {
+        var input;
+        if (p["in"] === "body") {
+          input = el("textarea");
+          input.value = schemaText(p.schema);
+        } else {
+          input = el("input", { placeholder: p.required ? "required" : "" });
+        }
+        inputs[p["in"] + ":" + p.name] = input;
+        var type = p["in"] === "body" ? (p.schema && p.schema.$ref ? p.schema.$ref.replace("#/definitions/", "") : "object") : (p.type || "") + (p.format ? " (" + p.format + ")" : "");
+        rows.push(el("tr", {}, [
+          el("td", { text: p.name + (p.required ? " *" : "") }),
+          el("td", { text: p["in"] }),
+          el("td", { text: type }),
+          el("td", { text: p.description || "" }),
+          config.specData ? null : el("td", {}, [input])
+        ]));
+      });
+      body.appendChild(el("h4", { text: "Parameters" }));
+      body.appendChild(el("table", {}, rows));
+      params.forEach(function (p) {
+        if (config.specData && p["in"] === "body") body.appendChild(el("pre", { text: schemaText(p.schema) }));
+      });
+    }
+
+    var responses = op.responses || {};
+    if (Object.keys(responses).length) {
+      body.appendChild(el("h4", { text: "Responses" }));
+      Object.keys(responses).forEach(function (code) {
+        var r = responses[code];
+        body.appendChild(el("div", {}, [el("strong", { text: code + " " }), el("span", { "class": "muted", text: r.description || "" })]));
+        if (r.schema) body.appendChild(el("pre", { text: schemaText(r.schema) }));
+      });
+    }
+
+    var result = el("pre", { "class": "muted", text: "" });
+    if (!config.specData) body.appendChild(el("button", { text: "Try it out", onclick: function () {
+      var url = (spec.basePath || "").replace(/\/$/, "") + path;
+      var query = [];
+      var headers = {};
+      var payload;
+      params.forEach(function (p) {
+        var v = inputs[p["in"] + ":" + p.name].value;
+        if (v === "" && p["in"] !== "body") return;
+        if (p["in"] === "path") url = url.split("{" + p.name + "}").join(encodeURIComponent(v));
+        else if (p["in"] === "query") query.push(encodeURIComponent(p.name) + "=" + encodeURIComponent(v));
+        else if (p["in"] === "header") headers[p.name] = v;
+        else if (p["in"] === "body") { payload = v; headers["Content-Type"] = "application/json"; }
+      });
+      if (query.length) url += "?" + query.join("&");
+      result.textContent = method.toUpperCase() + " " + url + "\n...";
+      fetch((config.proxy || "") + url, { method: method.toUpperCase(), headers: headers, body: payload }).then(function (res) {
+        return res.text().then(function (text) {
+          try { text = JSON.stringify(JSON.parse(text), null, 2); } catch (e) {}
+          result.textContent = method.toUpperCase() + " " + url + "\n" + res.status + " " + res.statusText + "\n\n" + text;
+        });
+      }).catch(function (err) {
+        result.textContent = String(err);
+      });
+    } }));
+    if (!config.specData) body.appendChild(result);
+
+    box.appendChild(head);
+    box.appendChild(body);
+    return box;
+  }
+
+  function render() {
+    var info = spec.info || {};
+    document.title = info.title || "API documentation";
+    document.getElementById("title").textContent = (info.title || "API documentation") + (info.version ? " " + info.version : "");
+    document.getElementById("description").textContent = info.description || "";
+
+    var groups = {};
+    var order = [];
+    var methods = ["get", "post", "put", "patch", "delete", "head", "options"];
+    Object.keys(spec.paths || {}).sort().forEach(function (path) {
+      methods.forEach(function (m) {
+        var op = spec.paths[path][m];
+        if (!op) return;
+        var tag = (op.tags && op.tags.length) ? op.tags[op.tags.length - 1] : "default";
+        if (!groups[tag]) { groups[tag] = []; order.push(tag); }
+        groups[tag].push(operation(path, m, op));
+      });
+    });
+
+    var content = document.getElementById("content");
+    content.innerHTML = "";
+    order.sort().forEach(function (tag) {
+      content.appendChild(el("h2", { text: tag }));
+      groups[tag].forEach(function (box) { content.appendChild(box); });
+    });
+    if (!order.length) content.appendChild(el("p", { "class": "muted", text: "The spec has no operations." }));
+  }
+
+  function status(text) {
+    var s = document.getElementById("status");
+    s.textContent = text;
+    s.style.display = "block";
+    setTimeout(function () { s.style.display = "none"; }, 1500);
+  }
+
+  function load(reloaded) {
+    if (config.specData) {
+      spec = config.specData;
+      render();
+      return;
+    }
+    fetch(config.spec, { cache: "no-store" }).then(function (res) {
+      if (!res.ok) throw new Error(res.status + " " + res.statusText);
+      return res.json();
+    }).then(function (s) {
+      spec = s;
+      render();
+      if (reloaded) status("spec reloaded");
+    }).catch(function (err) {
+      setTimeout(function () { load(reloaded); }, 1000);
+      status("waiting for spec: " + err.message);
+    });
+  }
+
+  load(false);
+  if (config.events) new EventSource(config.events).addEventListener("reload", function () { load(true); });
+})();
+</script>
+</body>
+</html>
+`
@@ -0,0 +1,133 @@
+package generator
+
+import (
+	"bytes"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/template"
+	"unicode"
+
+	"github.com/go-openapi/spec"
+)
+
+// GoSource renders the spec as a Go file of package pkg, holding the spec
+// as JSON and YAML and a handler serving both with the documentation page,
+// so a service can serve its own spec without reading files at runtime.
+func GoSource(sw *spec.Swagger, pkg string) ([]byte, error) {
+	js, err := Marshal(sw, false, true)
+	if err != nil {
+		return nil, err
+	}
+	ym, err := Marshal(sw, true, false)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	err = goSourceTemplate.Execute(&buf, map[string]string{
+		"Package": pkg,
+		"JSON":    quoteLines(string(js)),
+		"YAML":    quoteLines(string(ym)),
+		"Page":    quoteLines(DocPage(DocPageConfig{Spec: "swagger.json"})),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return format.Source(buf.Bytes())
+}
+
+// quoteLines quotes s as a concatenation of one literal per line, which
+// keeps the generated file readable and diffable.
+func quoteLines(s string) string {
+	lines := strings.SplitAfter(s, "\n")
+	if len(lines) > 1 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	quoted := make([]string, len(lines))
+	for i, line := range lines {
+		quoted[i] = strconv.Quote(line)
+	}
+	return strings.Join(quoted, " +\n\t")
+}
+
+// PackageName is the package of the Go files already in dir, or else a
+// name derived from the directory.
+func PackageName(dir string) string {
+	files, _ := filepath.Glob(filepath.Join(dir, "*.go"))
+	fset := token.NewFileSet()
+	for _, name := range files {
+		if strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		src, err := ioutil.ReadFile(name)
+		if err != nil {
+			continue
+		}
+		f, err := parser.ParseFile(fset, name, src, parser.PackageClauseOnly)
+		if err == nil {
+			return f.Name.Name
+		}
+	}
+
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "docs"
+	}
+	name := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, filepath.Base(abs))
+	if name == "" || unicode.IsDigit(rune(name[0])) {
+		return "docs"
+	}
+	return name
+}
+
+var goSourceTemplate = template.Must(template.New("").Parse(`// Code generated by go2swag. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+	"io"
+	"net/http"
+	"strings"
+)
+
+// SpecJSON is the swagger 2.0 spec of the API as JSON.
+var SpecJSON = []byte(specJSON)
+
+// SpecYAML is the same spec as YAML.
+var SpecYAML = []byte(specYAML)
+
+// Handler serves the spec at swagger.json and swagger.yaml below the path
+// it is mounted on, and the documentation page for anything else. Mount it
+// with a trailing slash, e.g. mux.Handle("/docs/", http.StripPrefix("/docs", Handler())).
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/swagger.json"):
+			w.Header().Set("Content-Type", "application/json")
+			w.Write(SpecJSON)
+		case strings.HasSuffix(r.URL.Path, "/swagger.yaml"):
+			w.Header().Set("Content-Type", "application/yaml")
+			w.Write(SpecYAML)
+		default:
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			io.WriteString(w, docPage)
+		}
+	})
+}
+
+const specJSON = {{.JSON}}
+
+const specYAML = {{.YAML}}
+
+const docPage = {{.Page}}
+`))
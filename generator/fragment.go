@@ -0,0 +1,591 @@
+package generator
+
+import (
+	"go/ast"
+	"go/constant"
+	"go/types"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/go-openapi/spec"
+	"golang.org/x/tools/go/ast/astutil"
+)
+
+// fragment is what the builder needs from a swag:req or swag:ans type. It is
+// worked out while the package is type checked, so that the builder itself
+// never touches go/types and scan results can be cached.
+type fragment struct {
+	// Schema is the definition built from the type, SchemaDiags what
+	// building it reported; they only surface when the schema is used.
+	Schema      spec.Schema   `json:"schema"`
+	SchemaDiags []*Diagnostic `json:"schemaDiags,omitempty"`
+	// Params are the fields of a request type as query or header parameters.
+	Params []fieldParam `json:"params,omitempty"`
+	// Rules are the constrained fields of a request type.
+	Rules []fieldRule `json:"rules,omitempty"`
+}
+
+// fieldParam is a parameter and the Go field it is read from.
+type fieldParam struct {
+	Field string         `json:"field"`
+	Type  fieldType      `json:"type"`
+	Param spec.Parameter `json:"param"`
+}
+
+// fieldType is what generated code needs to parse a parameter into a field.
+type fieldType struct {
+	// Basic is the underlying basic type, e.g. int32.
+	Basic string `json:"basic,omitempty"`
+	// PkgPath, PkgName and Name are set for named types, e.g. enums.
+	PkgPath string `json:"pkgPath,omitempty"`
+	PkgName string `json:"pkgName,omitempty"`
+	Name    string `json:"name,omitempty"`
+	Pointer bool   `json:"pointer,omitempty"`
+	// Text is set when the type implements encoding.TextUnmarshaler.
+	Text bool `json:"text,omitempty"`
+}
+
+// fieldRule is a field of a request type and the constraints of its schema
+// or parameter, which generated validation code checks.
+type fieldRule struct {
+	Field string `json:"field,omitempty"`
+	// Name is the JSON, query or header name of the field.
+	Name string    `json:"name,omitempty"`
+	Type fieldType `json:"type"`
+	// Kind is slice, array, map or struct for those types.
+	Kind     string `json:"kind,omitempty"`
+	Required bool   `json:"required,omitempty"`
+	// OmitEmpty is set for fields left out of JSON when they are zero.
+	OmitEmpty bool `json:"omitEmpty,omitempty"`
+	// Format is set for the formats validation code can check.
+	Format string `json:"format,omitempty"`
+	spec.CommonValidations
+	// Fields are the constrained fields of a struct, Elem the constraints
+	// on the elements of a slice or array.
+	Fields []fieldRule `json:"fields,omitempty"`
+	Elem   *fieldRule  `json:"elem,omitempty"`
+}
+
+func (self *fieldRule) empty() bool {
+	return !self.Required && self.Format == "" && reflect.DeepEqual(self.CommonValidations, spec.CommonValidations{}) &&
+		len(self.Fields) == 0 && self.Elem == nil
+}
+
+func newFieldType(t types.Type) fieldType {
+	ft := fieldType{}
+	if p, ok := t.(*types.Pointer); ok {
+		ft.Pointer = true
+		t = p.Elem()
+	}
+	if named, ok := t.(*types.Named); ok && named.Obj().Pkg() != nil {
+		ft.PkgPath = named.Obj().Pkg().Path()
+		ft.PkgName = named.Obj().Pkg().Name()
+		ft.Name = named.Obj().Name()
+	}
+	if m, _, _ := types.LookupFieldOrMethod(types.NewPointer(t), true, nil, "UnmarshalText"); m != nil {
+		if _, ok := m.(*types.Func); ok {
+			ft.Text = true
+			return ft
+		}
+	}
+	if b, ok := t.Underlying().(*types.Basic); ok && b.Info()&(types.IsBoolean|types.IsNumeric|types.IsString) != 0 && b.Info()&types.IsComplex == 0 {
+		ft.Basic = types.Typ[b.Kind()].Name()
+	}
+	return ft
+}
+
+type schemaBuilder struct {
+	diags   *Diagnostics
+	mappers []TypeMapper
+}
+
+func newFragment(decl *Decl, mappers []TypeMapper) *fragment {
+	frag := new(fragment)
+	b := schemaBuilder{diags: new(Diagnostics), mappers: mappers}
+	if b.buildSchemaFromDecl(decl.Name, decl, &frag.Schema) == nil {
+		frag.SchemaDiags = b.diags.List()
+	}
+	if decl.HasReq {
+		frag.Params = b.requestParams(decl)
+		frag.Rules = b.requestRules(decl, &frag.Schema, frag.Params)
+	}
+	return frag
+}
+
+// requestParams makes a query parameter of every field, or a header
+// parameter of fields tagged header:"Name", which json:"-" does not hide.
+func (self *schemaBuilder) requestParams(decl *Decl) []fieldParam {
+	params := []fieldParam{}
+	switch tpe := decl.Type.Obj().Type().(type) {
+	case *types.Named:
+		o := tpe.Obj()
+		switch stpe := o.Type().Underlying().(type) {
+		case *types.Struct:
+
+			for i := 0; i < stpe.NumFields(); i++ {
+				fld := stpe.Field(i)
+				// tg := stpe.Tag(i)
+
+				if fld.Embedded() {
+					continue
+				}
+
+				if !fld.Exported() {
+					continue
+				}
+
+				var afld *ast.Field
+				ans, _ := astutil.PathEnclosingInterval(decl.File, fld.Pos(), fld.Pos())
+				for _, an := range ans {
+					at, valid := an.(*ast.Field)
+					if !valid {
+						continue
+					}
+
+					afld = at
+					break
+				}
+
+				if afld == nil {
+					continue
+				}
+
+				name, ignore := parseJsonTags(afld)
+				in := "query"
+				if header := parseHeaderTag(afld); header != "" {
+					name, ignore, in = header, false, "header"
+				}
+				if ignore {
+					continue
+				}
+				param := func(name string) *spec.Parameter {
+					if in == "header" {
+						// optional like query parameters
+						p := spec.HeaderParam(name)
+						p.Required = false
+						return p
+					}
+					return spec.QueryParam(name)
+				}
+
+				var queryParam *spec.Parameter
+				switch titpe := fld.Type().Underlying().(type) {
+				case *types.Basic:
+					switch titpe.String() {
+					case "bool":
+						queryParam = param(name).Typed("boolean", "")
+					case "byte":
+						queryParam = param(name).Typed("integer", "uint8")
+					case "complex128", "complex64":
+					case "error":
+						queryParam = param(name).Typed("string", "")
+					case "float32":
+						queryParam = param(name).Typed("number", "float")
+					case "float64":
+						queryParam = param(name).Typed("number", "double")
+					case "int":
+						queryParam = param(name).Typed("integer", "int64")
+					case "int16":
+						queryParam = param(name).Typed("integer", "int16")
+					case "int32":
+						queryParam = param(name).Typed("integer", "int32")
+					case "int64":
+						queryParam = param(name).Typed("integer", "int64")
+					case "int8":
+						queryParam = param(name).Typed("integer", "int8")
+					case "rune":
+						queryParam = param(name).Typed("integer", "int32")
+					case "string":
+						queryParam = param(name).Typed("string", "")
+					case "uint":
+						queryParam = param(name).Typed("integer", "uint64")
+					case "uint16":
+						queryParam = param(name).Typed("integer", "uint16")
+					case "uint32":
+						queryParam = param(name).Typed("integer", "uint32")
+					case "uint64":
+						queryParam = param(name).Typed("integer", "uint64")
+					case "uint8":
+						queryParam = param(name).Typed("integer", "uint8")
+					case "uintptr":
+						queryParam = param(name).Typed("integer", "uint64")
+					default:
+					}
+				}
+
+				if named, ok := fld.Type().(*types.Named); ok && queryParam != nil {
+					queryParam.Enum = enumValues(named)
+				}
+
+				var mapped spec.Schema
+				if self.mapType(fld.Type(), &mapped) && len(mapped.Type) > 0 {
+					queryParam = param(name).Typed(mapped.Type[0], mapped.Format)
+				}
+
+				if queryParam != nil {
+					// malformed tags are reported with the schema
+					c, _ := parseConstraints(afld)
+					c.applyParam(queryParam)
+					params = append(params, fieldParam{Field: fld.Name(), Type: newFieldType(fld.Type()), Param: *queryParam.WithDescription(fieldComment(afld))})
+				}
+			}
+		}
+	}
+	return params
+}
+
+func (self *schemaBuilder) buildSchemaFromDecl(name string, decl *Decl, schema *spec.Schema) error {
+	switch tpe := decl.Type.Obj().Type().(type) {
+	case *types.Basic:
+	case *types.Named:
+		o := tpe.Obj()
+		if o != nil {
+			if o.Pkg().Name() == "time" && o.Name() == "Time" {
+				schema.Typed("string", "date-time")
+				return nil
+			}
+
+			for {
+				ti := decl.Pkg.TypesInfo.Types[decl.Spec.Type]
+
+				if ti.IsBuiltin() {
+					break
+				}
+				if ti.IsType() {
+					if err := self.buildSchemaFromType(decl, ti.Type, schema); err != nil {
+						return err
+					}
+					break
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (self *schemaBuilder) buildSchemaFromType(decl *Decl, tpe types.Type, schema *spec.Schema) error {
+	if self.mapType(tpe, schema) {
+		return nil
+	}
+
+	switch titpe := tpe.(type) {
+	case *types.Basic:
+		switch titpe.String() {
+		case "bool":
+			schema.Typed("boolean", "")
+		case "byte":
+			schema.Typed("integer", "uint8")
+		case "complex128", "complex64":
+		case "error":
+			// TODO: error is often marshalled into a string but not always (e.g. errors package creates
+			// errors that are marshalled into an empty object), this could be handled the same way
+			// custom JSON marshallers are handled (in future)
+			schema.Typed("string", "")
+		case "float32":
+			schema.Typed("number", "float")
+		case "float64":
+			schema.Typed("number", "double")
+		case "int":
+			schema.Typed("integer", "int64")
+		case "int16":
+			schema.Typed("integer", "int16")
+		case "int32":
+			schema.Typed("integer", "int32")
+		case "int64":
+			schema.Typed("integer", "int64")
+		case "int8":
+			schema.Typed("integer", "int8")
+		case "rune":
+			schema.Typed("integer", "int32")
+		case "string":
+			schema.Typed("string", "")
+		case "uint":
+			schema.Typed("integer", "uint64")
+		case "uint16":
+			schema.Typed("integer", "uint16")
+		case "uint32":
+			schema.Typed("integer", "uint32")
+		case "uint64":
+			schema.Typed("integer", "uint64")
+		case "uint8":
+			schema.Typed("integer", "uint8")
+		case "uintptr":
+			schema.Typed("integer", "uint64")
+		default:
+		}
+	case *types.Pointer:
+		return self.buildSchemaFromType(decl, titpe.Elem(), schema)
+	case *types.Struct:
+		self.buildSchemaFromStruct(decl, titpe, schema)
+	case *types.Slice:
+		if schema.Items == nil {
+			schema.Items = new(spec.SchemaOrArray)
+		}
+		if schema.Items.Schema == nil {
+			schema.Items.Schema = new(spec.Schema)
+		}
+		schema.Typed("array", "")
+		return self.buildSchemaFromType(decl, titpe.Elem(), schema.Items.Schema)
+	case *types.Array:
+		if schema.Items == nil {
+			schema.Items = new(spec.SchemaOrArray)
+		}
+		if schema.Items.Schema == nil {
+			schema.Items.Schema = new(spec.Schema)
+		}
+		schema.Typed("array", "")
+		return self.buildSchemaFromType(decl, titpe.Elem(), schema.Items.Schema)
+	case *types.Named:
+		switch utitpe := tpe.Underlying().(type) {
+		case *types.Struct:
+			return self.buildSchemaFromStruct(decl, utitpe, schema)
+		case *types.Basic:
+			self.buildSchemaFromType(decl, utitpe, schema)
+			schema.Enum = enumValues(titpe)
+		}
+	default:
+		self.diags.Warnf(decl.Pos(), CodeUnsupportedType, "%s: unsupported type %s", decl.Name, tpe)
+	}
+	return nil
+}
+
+func (self *schemaBuilder) buildSchemaFromStruct(decl *Decl, st *types.Struct, schema *spec.Schema) error {
+	if schema.Properties == nil {
+		schema.Properties = make(map[string]spec.Schema)
+	}
+	schema.Typed("object", "")
+
+	order := 0
+	for i := 0; i < st.NumFields(); i++ {
+		fld := st.Field(i)
+		// tg := st.Tag(i)
+
+		if fld.Embedded() {
+			continue
+		}
+
+		if !fld.Exported() {
+			continue
+		}
+
+		var afld *ast.Field
+		ans, _ := astutil.PathEnclosingInterval(decl.File, fld.Pos(), fld.Pos())
+		for _, an := range ans {
+			at, valid := an.(*ast.Field)
+			if !valid {
+				continue
+			}
+
+			afld = at
+			break
+		}
+
+		if afld == nil {
+			continue
+		}
+
+		name, ignore := parseJsonTags(afld)
+		if ignore {
+			continue
+		}
+
+		ps := schema.Properties[name]
+		self.buildSchemaFromType(decl, fld.Type(), &ps)
+		ps.Description = fieldComment(afld)
+		if _, ok := fld.Type().(*types.Pointer); ok {
+			ps.AddExtension("x-nullable", true)
+		}
+		if hasJsonOption(afld, "omitempty") {
+			ps.AddExtension("x-omitempty", true)
+		}
+		c, err := parseConstraints(afld)
+		if err != nil {
+			self.diags.Warnf(decl.Pkg.Fset.Position(fld.Pos()), CodeBadConstraint, "%s.%s: %v", decl.Name, fld.Name(), err)
+		}
+		c.applySchema(&ps)
+		if c.Required && !containsString(schema.Required, name) {
+			schema.Required = append(schema.Required, name)
+		}
+		ps.AddExtension("x-order", order)
+		ps.AddExtension(generatedExt, true)
+		order++
+		schema.Properties[name] = ps
+
+	}
+
+	return nil
+}
+
+// requestRules collect the constraints on the fields of a request type
+// from its schema, or from the parameter for header fields.
+func (self *schemaBuilder) requestRules(decl *Decl, schema *spec.Schema, params []fieldParam) []fieldRule {
+	st, ok := decl.Type.Obj().Type().Underlying().(*types.Struct)
+	if !ok {
+		return nil
+	}
+	headers := map[string]spec.Parameter{}
+	for _, p := range params {
+		if p.Param.In == "header" {
+			headers[p.Field] = p.Param
+		}
+	}
+	return self.structRules(decl, st, schema, headers, map[*types.Struct]bool{})
+}
+
+func (self *schemaBuilder) structRules(decl *Decl, st *types.Struct, schema *spec.Schema, headers map[string]spec.Parameter, seen map[*types.Struct]bool) []fieldRule {
+	if seen[st] {
+		return nil
+	}
+	seen[st] = true
+	defer delete(seen, st)
+
+	rules := []fieldRule{}
+	for i := 0; i < st.NumFields(); i++ {
+		fld := st.Field(i)
+		if fld.Embedded() || !fld.Exported() {
+			continue
+		}
+
+		var rule fieldRule
+		if p, ok := headers[fld.Name()]; ok {
+			rule = fieldRule{Name: p.Name, Required: p.Required, Format: checkedFormat(p.Format), CommonValidations: p.CommonValidations}
+		} else {
+			afld := astField(decl, fld)
+			if afld == nil {
+				continue
+			}
+			name, ignore := parseJsonTags(afld)
+			prop, ok := schema.Properties[name]
+			if ignore || !ok {
+				continue
+			}
+			rule = self.valueRule(decl, fld.Type(), &prop, seen)
+			rule.Name = name
+			rule.Required = containsString(schema.Required, name)
+			rule.OmitEmpty = hasJsonOption(afld, "omitempty")
+		}
+		rule.Field = fld.Name()
+		rule.Type = newFieldType(fld.Type())
+		if !rule.empty() {
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
+
+func (self *schemaBuilder) valueRule(decl *Decl, t types.Type, schema *spec.Schema, seen map[*types.Struct]bool) fieldRule {
+	rule := fieldRule{Type: newFieldType(t), Format: checkedFormat(schema.Format), CommonValidations: schemaValidations(schema)}
+	if p, ok := t.(*types.Pointer); ok {
+		t = p.Elem()
+	}
+	elem := func(t types.Type) {
+		if schema.Items == nil || schema.Items.Schema == nil {
+			return
+		}
+		e := self.valueRule(decl, t, schema.Items.Schema, seen)
+		if !e.empty() {
+			rule.Elem = &e
+		}
+	}
+	switch u := t.Underlying().(type) {
+	case *types.Struct:
+		rule.Kind = "struct"
+		rule.Fields = self.structRules(decl, u, schema, nil, seen)
+	case *types.Slice:
+		rule.Kind = "slice"
+		elem(u.Elem())
+	case *types.Array:
+		rule.Kind = "array"
+		elem(u.Elem())
+	case *types.Map:
+		rule.Kind = "map"
+	}
+	return rule
+}
+
+// astField is the declaration of a field in the file of decl, if it is there.
+func astField(decl *Decl, fld *types.Var) *ast.Field {
+	path, _ := astutil.PathEnclosingInterval(decl.File, fld.Pos(), fld.Pos())
+	for _, n := range path {
+		if f, ok := n.(*ast.Field); ok {
+			return f
+		}
+	}
+	return nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+func (self *schemaBuilder) mapType(tpe types.Type, schema *spec.Schema) bool {
+	for _, m := range self.mappers {
+		if m(tpe, schema) {
+			return true
+		}
+	}
+	return false
+}
+
+// enumValues are the exported constants of a named basic type, in
+// declaration order, which is how Go spells an enum.
+func enumValues(named *types.Named) []interface{} {
+	pkg := named.Obj().Pkg()
+	if pkg == nil {
+		return nil
+	}
+	consts := []*types.Const{}
+	for _, name := range pkg.Scope().Names() {
+		c, ok := pkg.Scope().Lookup(name).(*types.Const)
+		if ok && c.Exported() && types.Identical(c.Type(), named) {
+			consts = append(consts, c)
+		}
+	}
+	sort.SliceStable(consts, func(i, j int) bool { return consts[i].Pos() < consts[j].Pos() })
+
+	values := []interface{}{}
+	seen := map[string]bool{}
+	for _, c := range consts {
+		if seen[c.Val().ExactString()] {
+			continue
+		}
+		seen[c.Val().ExactString()] = true
+		switch c.Val().Kind() {
+		case constant.String:
+			values = append(values, constant.StringVal(c.Val()))
+		case constant.Int:
+			if v, ok := constant.Int64Val(c.Val()); ok {
+				values = append(values, v)
+			}
+		case constant.Float:
+			v, _ := constant.Float64Val(c.Val())
+			values = append(values, v)
+		case constant.Bool:
+			values = append(values, constant.BoolVal(c.Val()))
+		}
+	}
+	if len(values) == 0 {
+		return nil
+	}
+	return values
+}
+
+// fieldComment is the doc comment of a field followed by its line comment.
+func fieldComment(field *ast.Field) string {
+	parts := []string{}
+	for _, c := range []*ast.CommentGroup{field.Doc, field.Comment} {
+		if text := c.Text(); text != "" {
+			parts = append(parts, strings.TrimSpace(text))
+		}
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return strings.Join(parts, "\n") + "\n"
+}
@@ -0,0 +1,158 @@
+// Package generator turns swag annotations in Go sources into a swagger 2.0
+// spec. It is what the go2swag command runs, exposed for build tooling and
+// tests that want to generate specs without shelling out.
+package generator
+
+import (
+	"context"
+	"go/types"
+
+	"github.com/go-openapi/spec"
+	"golang.org/x/tools/go/packages"
+)
+
+// TypeMapper lets callers decide the schema of a Go type. It returns true
+// when it filled in schema, false to fall back to the built-in mapping.
+type TypeMapper func(tpe types.Type, schema *spec.Schema) bool
+
+// PostProcessor runs on the finished spec, after inputs and overlays have
+// been applied, with the scan it was built from.
+type PostProcessor func(sw *spec.Swagger, scan *Scanner) error
+
+type Config struct {
+	// Dir is where packages are loaded from, the current directory if empty.
+	Dir string
+	// Patterns selects the packages to load, ./... if empty.
+	Patterns []string
+	// Include and Exclude are package path patterns, in go's ... syntax,
+	// limiting which of the loaded packages and their imports are scanned
+	// for annotations. Include defaults to the main module.
+	Include []string
+	Exclude []string
+	// Inputs are base specs merged in order before generation.
+	Inputs []string
+	// OnConflict settles disagreeing inputs: "error" (default), "first" or "last".
+	OnConflict string
+	// Overlays are OpenAPI Overlay documents applied after generation.
+	Overlays []string
+	// Jobs bounds how many files are scanned at once, GOMAXPROCS if zero.
+	Jobs int
+	// CacheDir keeps package scans between runs, keyed by their sources.
+	// Caching is off when it is empty or TypeMappers are set, as mappers
+	// cannot be part of the key.
+	CacheDir string
+	// Plugins are external commands run on the finished spec, see PluginVersion.
+	Plugins []string
+
+	TypeMappers    []TypeMapper
+	PostProcessors []PostProcessor
+}
+
+// LoadMode is what Scan needs go/packages to load for the packages holding
+// annotations: their files and the export data of their imports. Scan parses
+// and type checks them itself, see typeCheck.
+const LoadMode = packages.NeedName | packages.NeedFiles | packages.NeedCompiledGoFiles | packages.NeedImports | packages.NeedExportsFile
+
+// Scan loads the configured packages and collects their annotations. The
+// import graph is listed first; only packages passing the include and
+// exclude patterns whose comments mention swag: are parsed and type checked.
+func Scan(ctx context.Context, cfg Config) (*Scanner, *Diagnostics, error) {
+	diags := new(Diagnostics)
+
+	patterns := cfg.Patterns
+	if len(patterns) == 0 {
+		patterns = []string{"./..."}
+	}
+
+	roots, err := packages.Load(&packages.Config{
+		Context: ctx,
+		Dir:     loadDir(cfg),
+		Mode:    metaLoadMode,
+		Tests:   false,
+	}, patterns...)
+	if err != nil {
+		return nil, diags, err
+	}
+
+	filter := newPackageFilter(loadDir(cfg), cfg.Include, cfg.Exclude, roots)
+	var cache *scanCache
+	if cfg.CacheDir != "" && len(cfg.TypeMappers) == 0 {
+		cache = newScanCache(cfg.CacheDir, loadDir(cfg))
+	}
+
+	scans := []*pkgScan{}
+	pending := map[string]*packages.Package{}
+	paths := []string{}
+	files := []string{}
+	for _, pkg := range selectPackages(roots, filter) {
+		files = append(files, pkg.GoFiles...)
+		if cache != nil {
+			if ps, ok := cache.get(pkg); ok {
+				scans = append(scans, ps)
+				continue
+			}
+		}
+		if needsScan(pkg) {
+			pending[pkg.PkgPath] = pkg
+			paths = append(paths, pkg.PkgPath)
+		}
+	}
+
+	if len(paths) > 0 {
+		pkgs, err := packages.Load(&packages.Config{
+			Context: ctx,
+			Dir:     loadDir(cfg),
+			Mode:    LoadMode,
+			Tests:   false,
+		}, paths...)
+		if err != nil {
+			return nil, diags, err
+		}
+		typeCheck(pkgs)
+		loaded, err := scanPackages(pkgs, cfg.TypeMappers, cfg.Jobs)
+		if err != nil {
+			return nil, diags, err
+		}
+		for i, ps := range loaded {
+			scans = append(scans, ps)
+			if meta, ok := pending[ps.Path]; ok && cache != nil && len(pkgs[i].Errors) == 0 {
+				cache.put(meta, ps)
+			}
+		}
+	}
+
+	s := merged(scans, diags)
+	s.files = files
+	return s, diags, nil
+}
+
+func loadDir(cfg Config) string {
+	if cfg.Dir == "" {
+		return "."
+	}
+	return cfg.Dir
+}
+
+// Generate scans the configured packages and builds the spec. Problems in
+// the sources are reported in the diagnostics; the error is only set when
+// no spec could be produced at all.
+func Generate(ctx context.Context, cfg Config) (*spec.Swagger, *Diagnostics, error) {
+	s, diags, err := Scan(ctx, cfg)
+	if err != nil {
+		return nil, diags, err
+	}
+
+	sw, err := build(s, load(cfg.Inputs, cfg.OnConflict, diags), diags)
+	if err != nil {
+		return nil, diags, err
+	}
+	sw = applyOverlays(sw, cfg.Overlays, diags)
+	sw = runPlugins(ctx, loadDir(cfg), sw, s, cfg.Plugins, diags)
+
+	for _, pp := range cfg.PostProcessors {
+		if err := pp(sw, s); err != nil {
+			return nil, diags, err
+		}
+	}
+	return sw, diags, nil
+}
@@ -0,0 +1,92 @@
+package generator
+
+import (
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-openapi/spec"
+	"golang.org/x/tools/go/packages"
+)
+
+// testPackage type checks the package in dir the way go/packages would,
+// importing its dependencies from source.
+func testPackage(t *testing.T, dir, path string) *packages.Package {
+	t.Helper()
+	fset := token.NewFileSet()
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := []string{}
+	files := []*ast.File{}
+	for _, fi := range infos {
+		if !strings.HasSuffix(fi.Name(), ".go") || strings.HasSuffix(fi.Name(), "_test.go") {
+			continue
+		}
+		name, err := filepath.Abs(filepath.Join(dir, fi.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		file, err := parser.ParseFile(fset, name, nil, parser.ParseComments)
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, name)
+		files = append(files, file)
+	}
+
+	info := &types.Info{
+		Types:      map[ast.Expr]types.TypeAndValue{},
+		Defs:       map[*ast.Ident]types.Object{},
+		Uses:       map[*ast.Ident]types.Object{},
+		Implicits:  map[ast.Node]types.Object{},
+		Selections: map[*ast.SelectorExpr]*types.Selection{},
+		Scopes:     map[ast.Node]*types.Scope{},
+	}
+	conf := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+	tpkg, err := conf.Check(path, fset, files, info)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &packages.Package{
+		ID:              path,
+		Name:            tpkg.Name(),
+		PkgPath:         path,
+		GoFiles:         names,
+		CompiledGoFiles: names,
+		Fset:            fset,
+		Syntax:          files,
+		Types:           tpkg,
+		TypesInfo:       info,
+	}
+}
+
+// testSpec scans the package in dir and builds its spec, failing on any
+// error diagnostic.
+func testSpec(t *testing.T, dir, path string) (*spec.Swagger, *Scanner) {
+	t.Helper()
+	scans, err := scanPackages([]*packages.Package{testPackage(t, dir, path)}, nil, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	diags := new(Diagnostics)
+	s := merged(scans, diags)
+	sw, err := build(s, nil, diags)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, d := range diags.List() {
+		if d.Severity == SeverityError {
+			t.Fatalf("%s", d)
+		}
+	}
+	return sw, s
+}
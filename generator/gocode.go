@@ -0,0 +1,241 @@
+package generator
+
+import (
+	"go/token"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// goRoutes works out how generated Go code calls or serves each route of
+// the scan. Routes that code outside their packages cannot express are
+// left out and reported.
+func goRoutes(scan *Scanner, imports *goImports, diags *Diagnostics) []goRoute {
+	responses := map[string][]*Decl{}
+	for _, ans := range scan.Responses() {
+		responses[ans.ID] = append(responses[ans.ID], ans)
+	}
+
+	routes := []goRoute{}
+	names := map[string]string{}
+	for _, r := range scan.Routes() {
+		if !validMethod(r.Method) {
+			continue
+		}
+		name := exportedName(r.ID)
+		if name == "" {
+			diags.Errorf(r.Pos, CodeCodegen, "route %s: id does not make a Go method name", r.ID)
+			continue
+		}
+		if other, ok := names[name]; ok {
+			diags.Errorf(r.Pos, CodeCodegen, "route %s: method name %s is already used by route %s", r.ID, name, other)
+			continue
+		}
+
+		route := goRoute{
+			ID:      r.ID,
+			Name:    name,
+			Method:  r.Method,
+			Path:    scan.RoutePath(r),
+			Comment: r.Comment(),
+			Pkg:     r.Pkg,
+			Pos:     r.Pos,
+		}
+
+		req, hasReq := scan.reqs[r.ID]
+		sort.Slice(responses[r.ID], func(i, j int) bool { return responses[r.ID][i].Code < responses[r.ID][j].Code })
+		usable := !hasReq || usableType(req, diags)
+		for _, ans := range responses[r.ID] {
+			usable = usableType(ans, diags) && usable
+		}
+		if !usable {
+			continue
+		}
+
+		if hasReq {
+			route.Request = imports.qualified(req.PkgPath, req.PkgName, req.TypeName)
+		}
+		for _, ans := range responses[r.ID] {
+			route.Responses = append(route.Responses, goResponse{Status: ans.Code, Type: imports.qualified(ans.PkgPath, ans.PkgName, ans.TypeName)})
+		}
+
+		fields := map[string]fieldParam{}
+		if hasReq && req.frag != nil {
+			for _, p := range req.frag.Params {
+				switch p.Param.In {
+				case "query":
+					fields[p.Param.Name] = p
+				case "header":
+					route.Header = append(route.Header, goField{Name: p.Param.Name, Field: p.Field, Type: p.Type})
+				}
+			}
+		}
+
+		inPath := map[string]bool{}
+		for _, seg := range strings.Split(route.Path, "/") {
+			for _, param := range segmentParams(seg) {
+				inPath[param.Name] = true
+				if p, ok := fields[param.Name]; ok {
+					route.PathFields = append(route.PathFields, goField{Name: param.Name, Field: p.Field, Type: p.Type, Placeholder: param.Placeholder})
+				} else {
+					route.Args = append(route.Args, goField{Name: param.Name, Field: imports.argName(param.Name, len(route.Args)), Placeholder: param.Placeholder})
+				}
+			}
+		}
+
+		if hasReq {
+			if r.Method == "GET" {
+				for _, p := range req.frag.Params {
+					if p.Param.In == "query" && !inPath[p.Param.Name] {
+						route.Query = append(route.Query, goField{Name: p.Param.Name, Field: p.Field, Type: p.Type})
+					}
+				}
+			} else {
+				route.Body = true
+			}
+		}
+
+		names[name] = r.ID
+		routes = append(routes, route)
+	}
+	return routes
+}
+
+type goImport struct {
+	Alias, Path string
+}
+
+type goRoute struct {
+	// ID is the operation id.
+	ID                 string
+	Name, Method, Path string
+	Comment            string
+	// Pkg is the package declaring the route.
+	Pkg string
+	Pos token.Position
+	// Request is the qualified swag:req type, empty without one.
+	Request string
+	// Args are path parameters no request field provides.
+	Args       []goField
+	PathFields []goField
+	Query      []goField
+	Header     []goField
+	Body       bool
+	Responses  []goResponse
+}
+
+// goField is a parameter and the request field or argument holding it.
+type goField struct {
+	Name, Field, Placeholder string
+	Type                     fieldType
+	// Bind parses the parameter into the field, in generated server code.
+	Bind string
+}
+
+type goResponse struct {
+	Status int
+	Type   string
+}
+
+// goImports hands out import aliases that clash neither with each other
+// nor with what the generated file declares and imports itself.
+type goImports struct {
+	aliases map[string]string
+	used    map[string]bool
+}
+
+func newGoImports(reserved ...string) *goImports {
+	used := map[string]bool{}
+	for _, name := range reserved {
+		used[name] = true
+	}
+	return &goImports{aliases: map[string]string{}, used: used}
+}
+
+// qualified is a type of package pkgPath as the generated file refers to it.
+func (self *goImports) qualified(pkgPath, pkgName, name string) string {
+	alias, ok := self.aliases[pkgPath]
+	if !ok {
+		base := pkgName
+		if base == "" {
+			base = "api"
+		}
+		alias = base
+		for i := 2; self.used[alias]; i++ {
+			alias = base + strconv.Itoa(i)
+		}
+		self.aliases[pkgPath] = alias
+		self.used[alias] = true
+	}
+	return alias + "." + name
+}
+
+func (self *goImports) list() []goImport {
+	list := []goImport{}
+	for path, alias := range self.aliases {
+		list = append(list, goImport{Alias: alias, Path: path})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Path < list[j].Path })
+	return list
+}
+
+// usableType reports whether code outside its package can refer to d.
+func usableType(d *Decl, diags *Diagnostics) bool {
+	if d.PkgName == "main" {
+		diags.Errorf(d.Pos(), CodeCodegen, "route %s: %s is declared in a main package, which cannot be imported", d.ID, d.TypeName)
+		return false
+	}
+	if !unicode.IsUpper([]rune(d.TypeName)[0]) {
+		diags.Errorf(d.Pos(), CodeCodegen, "route %s: %s is not exported", d.ID, d.TypeName)
+		return false
+	}
+	return true
+}
+
+func validMethod(method string) bool {
+	switch method {
+	case "GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS":
+		return true
+	}
+	return false
+}
+
+// exportedName turns a route id like get-user or getUser into GetUser.
+func exportedName(id string) string {
+	var b strings.Builder
+	upper := true
+	for _, r := range id {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if b.Len() == 0 && unicode.IsDigit(r) {
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// argName is a parameter name usable as a Go identifier that shadows
+// neither an import nor a variable of the client methods.
+func (self *goImports) argName(param string, i int) string {
+	name := []rune(exportedName(param))
+	if len(name) == 0 {
+		return "param" + strconv.Itoa(i)
+	}
+	name[0] = unicode.ToLower(name[0])
+	switch string(name) {
+	case "c", "ctx", "req", "path", "query", "header", "body", "res", "out", "err":
+		return string(name) + "Param"
+	}
+	if self.used[string(name)] || token.Lookup(string(name)).IsKeyword() {
+		return string(name) + "Param"
+	}
+	return string(name)
+}
@@ -0,0 +1,124 @@
+package generator
+
+import (
+	"fmt"
+	"go/token"
+	"os"
+	"reflect"
+
+	"github.com/go-openapi/loads"
+	"github.com/go-openapi/spec"
+)
+
+const (
+	conflictError = "error"
+	conflictFirst = "first"
+	conflictLast  = "last"
+)
+
+// load reads and merges the input specs in order. Unreadable inputs are
+// reported and skipped; a missing input only warns so a first run can
+// create the file it will read next time.
+func load(inputs []string, policy string, diags *Diagnostics) *spec.Swagger {
+	switch policy {
+	case "":
+		policy = conflictError
+	case conflictError, conflictFirst, conflictLast:
+	default:
+		diags.Errorf(token.Position{}, CodeInput, "unknown conflict policy %q, want error, first or last", policy)
+		return nil
+	}
+
+	var merged map[string]interface{}
+	owners := map[string]string{}
+	for _, input := range inputs {
+		if input == "" {
+			continue
+		}
+		fi, err := os.Stat(input)
+		if os.IsNotExist(err) {
+			diags.Warnf(token.Position{Filename: input}, CodeInput, "input spec does not exist")
+			continue
+		}
+		if err == nil && fi.IsDir() {
+			err = fmt.Errorf("is a directory")
+		}
+		if err != nil {
+			diags.Errorf(token.Position{Filename: input}, CodeInput, "%v", err)
+			continue
+		}
+
+		sp, err := loads.Spec(input)
+		if err != nil {
+			diags.Errorf(token.Position{Filename: input}, CodeInput, "%v", err)
+			continue
+		}
+		doc, err := genericJSON(sp.Spec())
+		if err != nil {
+			diags.Errorf(token.Position{Filename: input}, CodeInput, "%v", err)
+			continue
+		}
+		obj, _ := doc.(map[string]interface{})
+
+		if merged == nil {
+			merged = obj
+			recordOwners(owners, nil, obj, input)
+			continue
+		}
+		mergeInput(merged, obj, nil, input, policy, owners, diags)
+	}
+
+	if merged == nil {
+		return nil
+	}
+	out := new(spec.Swagger)
+	if err := fromGeneric(merged, out); err != nil {
+		diags.Errorf(token.Position{}, CodeInput, "%v", err)
+		return nil
+	}
+	return out
+}
+
+func recordOwners(owners map[string]string, path []string, v interface{}, file string) {
+	owners[joinPath(path)] = file
+	if obj, ok := v.(map[string]interface{}); ok {
+		for _, k := range sortedMapKeys(obj) {
+			recordOwners(owners, append(append([]string{}, path...), k), obj[k], file)
+		}
+	}
+}
+
+// mergeInput deep merges src into dst. Objects are merged key by key, any
+// other differing value is a conflict settled by policy.
+func mergeInput(dst, src map[string]interface{}, path []string, file, policy string, owners map[string]string, diags *Diagnostics) {
+	for _, k := range sortedMapKeys(src) {
+		sv := src[k]
+		sub := append(append([]string{}, path...), k)
+		dv, exists := dst[k]
+		if !exists {
+			dst[k] = sv
+			recordOwners(owners, sub, sv, file)
+			continue
+		}
+
+		dobj, dok := dv.(map[string]interface{})
+		sobj, sok := sv.(map[string]interface{})
+		if dok && sok {
+			mergeInput(dobj, sobj, sub, file, policy, owners, diags)
+			continue
+		}
+		if reflect.DeepEqual(dv, sv) {
+			continue
+		}
+
+		switch policy {
+		case conflictLast:
+			dst[k] = sv
+			recordOwners(owners, sub, sv, file)
+		case conflictError:
+			where := joinPath(sub)
+			diags.Errorf(token.Position{Filename: file}, CodeInputConflict, "%s conflicts with %s", where, owners[where]).
+				Relate(token.Position{Filename: owners[where]})
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package generator
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLoadConflictOrder(t *testing.T) {
+	dir, err := ioutil.TempDir("", "go2swag-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	write := func(name, doc string) string {
+		file := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(file, []byte(doc), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return file
+	}
+	a := write("a.json", `{"swagger": "2.0", "info": {"title": "A", "version": "1", "description": "a"}, "host": "a", "basePath": "/a", "paths": {}}`)
+	b := write("b.json", `{"swagger": "2.0", "info": {"title": "B", "version": "2", "description": "b"}, "host": "b", "basePath": "/b", "paths": {}}`)
+
+	want := []string{
+		"basePath conflicts with " + a,
+		"host conflicts with " + a,
+		"info.description conflicts with " + a,
+		"info.title conflicts with " + a,
+		"info.version conflicts with " + a,
+	}
+	// map order is random, so one lucky run would not tell
+	for i := 0; i < 10; i++ {
+		diags := new(Diagnostics)
+		load([]string{a, b}, conflictError, diags)
+		got := []string{}
+		for _, d := range diags.List() {
+			if d.Code != CodeInputConflict {
+				t.Fatalf("unexpected diagnostic %s", d)
+			}
+			got = append(got, d.Message)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("got %q, want %q", got, want)
+		}
+	}
+}
@@ -0,0 +1,412 @@
+package generator
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"go/token"
+	"sort"
+
+	"github.com/go-openapi/spec"
+)
+
+// generatedExt marks spec parts owned by go2swag. Its value is a hash of the
+// generated fields, the common base for a three-way merge with hand edits.
+const generatedExt = "x-go2swag-generated"
+
+// Fields the generator owns per kind of node; everything else is left to
+// the humans maintaining the input spec.
+var (
+	ownedOperation = []string{"summary", "description", "operationId", "tags"}
+	ownedParameter = append([]string{"name", "in", "description", "required", "type", "format", "items", "schema", "enum"}, ownedValidations...)
+	ownedResponse  = []string{"description", "schema"}
+	ownedSchema    = append([]string{"$ref", "type", "format", "description", "items", "required", "enum", "x-nullable", "x-omitempty", "x-order"}, ownedValidations...)
+
+	// ownedValidations are written from validate and pattern tags.
+	ownedValidations = []string{
+		"maximum", "exclusiveMaximum", "minimum", "exclusiveMinimum",
+		"maxLength", "minLength", "pattern",
+		"maxItems", "minItems", "uniqueItems", "multipleOf",
+	}
+)
+
+var httpMethods = []string{"get", "put", "post", "delete", "options", "head", "patch"}
+
+type merger struct {
+	prev  map[string]interface{}
+	diags *Diagnostics
+}
+
+// newMerger snapshots input and returns a copy with every generated part
+// removed, so that whatever the builder no longer produces is pruned.
+func newMerger(input *spec.Swagger, diags *Diagnostics) (*merger, *spec.Swagger, error) {
+	doc, err := genericJSON(input)
+	if err != nil {
+		return nil, nil, err
+	}
+	prev, _ := doc.(map[string]interface{})
+	m := &merger{prev: prev, diags: diags}
+
+	stripped, err := genericJSON(input)
+	if err != nil {
+		return nil, nil, err
+	}
+	root, _ := stripped.(map[string]interface{})
+	stripGenerated(root)
+
+	out := new(spec.Swagger)
+	if err := fromGeneric(root, out); err != nil {
+		return nil, nil, err
+	}
+	return m, out, nil
+}
+
+func fromGeneric(v interface{}, out interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, out)
+}
+
+func isGenerated(v interface{}) bool {
+	obj, ok := v.(map[string]interface{})
+	if !ok {
+		return false
+	}
+	_, ok = obj[generatedExt]
+	return ok
+}
+
+func stripGenerated(root map[string]interface{}) {
+	paths, _ := root["paths"].(map[string]interface{})
+	for path, v := range paths {
+		item, _ := v.(map[string]interface{})
+		for _, method := range httpMethods {
+			op, ok := item[method]
+			if !ok {
+				continue
+			}
+			if isGenerated(op) {
+				delete(item, method)
+				continue
+			}
+			stripOperation(op.(map[string]interface{}))
+		}
+		if len(item) == 0 {
+			delete(paths, path)
+		}
+	}
+
+	defs, _ := root["definitions"].(map[string]interface{})
+	for name, v := range defs {
+		if isGenerated(v) {
+			delete(defs, name)
+			continue
+		}
+		if schema, ok := v.(map[string]interface{}); ok {
+			stripSchema(schema)
+		}
+	}
+}
+
+func stripOperation(op map[string]interface{}) {
+	if params, ok := op["parameters"].([]interface{}); ok {
+		kept := []interface{}{}
+		for _, p := range params {
+			if !isGenerated(p) {
+				kept = append(kept, p)
+			}
+		}
+		op["parameters"] = kept
+		if len(kept) == 0 {
+			delete(op, "parameters")
+		}
+	}
+	if responses, ok := op["responses"].(map[string]interface{}); ok {
+		for code, r := range responses {
+			if isGenerated(r) {
+				delete(responses, code)
+			}
+		}
+	}
+}
+
+func stripSchema(schema map[string]interface{}) {
+	props, _ := schema["properties"].(map[string]interface{})
+	for name, p := range props {
+		if isGenerated(p) {
+			delete(props, name)
+		} else if ps, ok := p.(map[string]interface{}); ok {
+			stripSchema(ps)
+		}
+	}
+}
+
+// merge folds the hand-written parts of the snapshot back into the freshly
+// built spec and stamps every generated part with its new base hash.
+func (self *merger) merge(built *spec.Swagger) (*spec.Swagger, error) {
+	doc, err := genericJSON(built)
+	if err != nil {
+		return nil, err
+	}
+	next, _ := doc.(map[string]interface{})
+
+	prevPaths, _ := self.prev["paths"].(map[string]interface{})
+	nextPaths, _ := next["paths"].(map[string]interface{})
+	for _, path := range sortedMapKeys(prevPaths) {
+		prevItem, _ := prevPaths[path].(map[string]interface{})
+		nextItem, _ := nextPaths[path].(map[string]interface{})
+		for _, method := range httpMethods {
+			prevOp, ok := prevItem[method].(map[string]interface{})
+			if !ok {
+				continue
+			}
+			nextOp, ok := nextItem[method].(map[string]interface{})
+			if !ok {
+				continue
+			}
+			where := "paths." + path + "." + method
+			if isGenerated(prevOp) && isGenerated(nextOp) {
+				self.mergeNode(where, prevOp, nextOp, ownedOperation, "parameters", "responses")
+			}
+			self.mergeParameters(where, prevOp, nextOp)
+			self.mergeResponses(where, prevOp, nextOp)
+		}
+	}
+
+	prevDefs, _ := self.prev["definitions"].(map[string]interface{})
+	nextDefs, _ := next["definitions"].(map[string]interface{})
+	for _, name := range sortedMapKeys(prevDefs) {
+		prevSchema, _ := prevDefs[name].(map[string]interface{})
+		nextSchema, ok := nextDefs[name].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		self.mergeSchema("definitions."+name, prevSchema, nextSchema)
+	}
+
+	stampGenerated(next)
+
+	out := new(spec.Swagger)
+	if err := fromGeneric(next, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// mergeNode performs the three-way merge of a single generated node. The
+// base is the hash stored in the snapshot's marker; children are skipped.
+func (self *merger) mergeNode(where string, prev, next map[string]interface{}, owned []string, children ...string) {
+	skip := map[string]bool{generatedExt: true}
+	for _, k := range owned {
+		skip[k] = true
+	}
+	for _, k := range children {
+		skip[k] = true
+	}
+
+	base, _ := prev[generatedExt].(string)
+	if base != "" {
+		prevHash, nextHash := ownedHash(prev, owned), ownedHash(next, owned)
+		if prevHash != base {
+			if nextHash == base {
+				// only the humans touched it, keep their version
+				for _, k := range owned {
+					if v, ok := prev[k]; ok {
+						next[k] = v
+					} else {
+						delete(next, k)
+					}
+				}
+				next[generatedExt] = base
+			} else {
+				self.diags.Warnf(token.Position{}, CodeMergeConflict, "%s: hand edits of generated fields conflict with source changes, the generated version wins", where)
+			}
+		}
+	}
+
+	for k, v := range prev {
+		if skip[k] {
+			continue
+		}
+		if _, ok := next[k]; !ok {
+			next[k] = v
+		}
+	}
+}
+
+func (self *merger) mergeParameters(where string, prevOp, nextOp map[string]interface{}) {
+	prevParams, _ := prevOp["parameters"].([]interface{})
+	nextParams, _ := nextOp["parameters"].([]interface{})
+	key := func(v interface{}) string {
+		p, _ := v.(map[string]interface{})
+		in, _ := p["in"].(string)
+		name, _ := p["name"].(string)
+		return in + " " + name
+	}
+
+	index := map[string]map[string]interface{}{}
+	for _, p := range nextParams {
+		if obj, ok := p.(map[string]interface{}); ok {
+			index[key(p)] = obj
+		}
+	}
+	for _, p := range prevParams {
+		prevParam, ok := p.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		nextParam, exists := index[key(p)]
+		switch {
+		case !isGenerated(prevParam):
+			if !exists {
+				nextParams = append(nextParams, prevParam)
+			}
+		case exists && isGenerated(nextParam):
+			self.mergeNode(where+".parameters."+key(p), prevParam, nextParam, ownedParameter)
+		}
+	}
+	if len(nextParams) > 0 {
+		nextOp["parameters"] = nextParams
+	}
+}
+
+func (self *merger) mergeResponses(where string, prevOp, nextOp map[string]interface{}) {
+	prevResps, _ := prevOp["responses"].(map[string]interface{})
+	if len(prevResps) == 0 {
+		return
+	}
+	nextResps, ok := nextOp["responses"].(map[string]interface{})
+	if !ok {
+		nextResps = map[string]interface{}{}
+		nextOp["responses"] = nextResps
+	}
+	for _, code := range sortedMapKeys(prevResps) {
+		prevResp, _ := prevResps[code].(map[string]interface{})
+		nextResp, exists := nextResps[code].(map[string]interface{})
+		switch {
+		case !isGenerated(prevResp):
+			if !exists {
+				nextResps[code] = prevResp
+			}
+		case exists && isGenerated(nextResp):
+			self.mergeNode(where+".responses."+code, prevResp, nextResp, ownedResponse)
+		}
+	}
+}
+
+func (self *merger) mergeSchema(where string, prev, next map[string]interface{}) {
+	if isGenerated(prev) && isGenerated(next) {
+		self.mergeNode(where, prev, next, ownedSchema, "properties")
+	}
+
+	prevProps, _ := prev["properties"].(map[string]interface{})
+	if len(prevProps) == 0 {
+		return
+	}
+	nextProps, ok := next["properties"].(map[string]interface{})
+	if !ok {
+		nextProps = map[string]interface{}{}
+		next["properties"] = nextProps
+	}
+	for _, name := range sortedMapKeys(prevProps) {
+		prevProp, _ := prevProps[name].(map[string]interface{})
+		nextProp, exists := nextProps[name].(map[string]interface{})
+		switch {
+		case !isGenerated(prevProp):
+			if !exists {
+				nextProps[name] = prevProp
+			}
+		case exists:
+			self.mergeSchema(where+".properties."+name, prevProp, nextProp)
+		}
+	}
+}
+
+// stampGenerated replaces every marker with the hash of the owned fields.
+func stampGenerated(root map[string]interface{}) {
+	stamp := func(v interface{}, owned []string) {
+		if obj, ok := v.(map[string]interface{}); ok && isGenerated(obj) {
+			if _, kept := obj[generatedExt].(string); !kept {
+				obj[generatedExt] = ownedHash(obj, owned)
+			}
+		}
+	}
+	var stampSchema func(v interface{})
+	stampSchema = func(v interface{}) {
+		stamp(v, ownedSchema)
+		obj, _ := v.(map[string]interface{})
+		props, _ := obj["properties"].(map[string]interface{})
+		for _, p := range props {
+			stampSchema(p)
+		}
+	}
+
+	paths, _ := root["paths"].(map[string]interface{})
+	for _, item := range paths {
+		item, _ := item.(map[string]interface{})
+		for _, method := range httpMethods {
+			op, ok := item[method].(map[string]interface{})
+			if !ok {
+				continue
+			}
+			stamp(op, ownedOperation)
+			params, _ := op["parameters"].([]interface{})
+			for _, p := range params {
+				stamp(p, ownedParameter)
+			}
+			resps, _ := op["responses"].(map[string]interface{})
+			for _, r := range resps {
+				stamp(r, ownedResponse)
+			}
+		}
+	}
+
+	defs, _ := root["definitions"].(map[string]interface{})
+	for _, d := range defs {
+		stampSchema(d)
+	}
+}
+
+func ownedHash(obj map[string]interface{}, owned []string) string {
+	picked := map[string]interface{}{}
+	for _, k := range owned {
+		if v, ok := obj[k]; ok {
+			picked[k] = withoutMarkers(v)
+		}
+	}
+	b, _ := json.Marshal(picked)
+	sum := sha1.Sum(b)
+	return hex.EncodeToString(sum[:8])
+}
+
+// withoutMarkers drops nested markers so a hash only depends on content.
+func withoutMarkers(v interface{}) interface{} {
+	switch tv := v.(type) {
+	case map[string]interface{}:
+		out := map[string]interface{}{}
+		for k, item := range tv {
+			if k != generatedExt {
+				out[k] = withoutMarkers(item)
+			}
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(tv))
+		for i, item := range tv {
+			out[i] = withoutMarkers(item)
+		}
+		return out
+	}
+	return v
+}
+
+func sortedMapKeys(m map[string]interface{}) []string {
+	keys := []string{}
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
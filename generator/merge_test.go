@@ -0,0 +1,202 @@
+package generator
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/go-openapi/spec"
+)
+
+func TestMerge(t *testing.T) {
+	generated, scan := testSpec(t, "testdata/api", "example.com/codegen/api")
+	op := func(doc map[string]interface{}, path, method string) map[string]interface{} {
+		return doc["paths"].(map[string]interface{})[path].(map[string]interface{})[method].(map[string]interface{})
+	}
+	// restamp makes an edit look like it was generated from older sources
+	restamp := func(obj map[string]interface{}, owned []string) {
+		obj[generatedExt] = ownedHash(obj, owned)
+	}
+
+	cases := []struct {
+		name string
+		// edit turns the generated spec into the input spec.
+		edit  func(doc map[string]interface{})
+		want  map[string]string
+		diags []string
+	}{
+		{
+			name: "hand edits of generated fields are kept",
+			edit: func(doc map[string]interface{}) {
+				op(doc, "/users/{id}", "get")["summary"] = "Fetch a user"
+			},
+			want: map[string]string{"$.paths['/users/{id}'].get.summary": `["Fetch a user"]`},
+		},
+		{
+			name: "source changes are taken",
+			edit: func(doc map[string]interface{}) {
+				get := op(doc, "/users/{id}", "get")
+				get["summary"] = "Old summary"
+				restamp(get, ownedOperation)
+			},
+			want: map[string]string{"$.paths['/users/{id}'].get.summary": `["Get a user"]`},
+		},
+		{
+			name: "conflicts are won by the sources",
+			edit: func(doc map[string]interface{}) {
+				get := op(doc, "/users/{id}", "get")
+				get["summary"] = "Old summary"
+				restamp(get, ownedOperation)
+				get["description"] = "Edited by hand"
+			},
+			want: map[string]string{
+				"$.paths['/users/{id}'].get.summary":     `["Get a user"]`,
+				"$.paths['/users/{id}'].get.description": `[]`,
+			},
+			diags: []string{"warning: paths./users/{id}.get: hand edits of generated fields conflict with source changes, the generated version wins [GS301]"},
+		},
+		{
+			name: "constraints removed from the sources are dropped",
+			edit: func(doc map[string]interface{}) {
+				defs := doc["definitions"].(map[string]interface{})
+				created := defs["createUser-201"].(map[string]interface{})
+				created["required"] = []interface{}{"id"}
+				restamp(created, ownedSchema)
+				name := defs["createUser"].(map[string]interface{})["properties"].(map[string]interface{})["name"].(map[string]interface{})
+				name["minLength"] = 2
+				restamp(name, ownedSchema)
+				limit := op(doc, "/files/{name}.json", "get")["parameters"].([]interface{})[1].(map[string]interface{})
+				limit["maximum"] = 100
+				restamp(limit, ownedParameter)
+			},
+			want: map[string]string{
+				"$.definitions['createUser-201'].required":                                   `[]`,
+				"$.definitions.createUser.properties.name.minLength":                         `[]`,
+				"$.definitions.createUser.properties.name.maxLength":                         `[64]`,
+				"$.paths['/files/{name}.json'].get.parameters[?(@.name == 'limit')].maximum": `[]`,
+				"$.paths['/files/{name}.json'].get.parameters[?(@.name == 'limit')].minimum": `[1]`,
+			},
+		},
+		{
+			name: "enums and nullability removed from the sources are dropped",
+			edit: func(doc map[string]interface{}) {
+				name := doc["definitions"].(map[string]interface{})["createUser"].(map[string]interface{})["properties"].(map[string]interface{})["name"].(map[string]interface{})
+				name["enum"] = []interface{}{"alice", "bob"}
+				name["x-nullable"] = true
+				name["x-omitempty"] = true
+				restamp(name, ownedSchema)
+				verbose := op(doc, "/users/{id}", "get")["parameters"].([]interface{})[1].(map[string]interface{})
+				verbose["enum"] = []interface{}{true}
+				restamp(verbose, ownedParameter)
+			},
+			want: map[string]string{
+				"$.definitions.createUser.properties.name.enum":                      `[]`,
+				"$.definitions.createUser.properties.name.x-nullable":                `[]`,
+				"$.definitions.createUser.properties.name.x-omitempty":               `[]`,
+				"$.definitions.createUser.properties.email.x-nullable":               `[true]`,
+				"$.paths['/users/{id}'].get.parameters[?(@.name == 'verbose')].enum": `[]`,
+			},
+		},
+		{
+			name: "hand edits of constraints are kept",
+			edit: func(doc map[string]interface{}) {
+				def := doc["definitions"].(map[string]interface{})["createUser"].(map[string]interface{})
+				def["properties"].(map[string]interface{})["name"].(map[string]interface{})["maxLength"] = 32
+			},
+			want: map[string]string{"$.definitions.createUser.properties.name.maxLength": `[32]`},
+		},
+		{
+			name: "hand-written parts are kept",
+			edit: func(doc map[string]interface{}) {
+				get := op(doc, "/users/{id}", "get")
+				get["deprecated"] = true
+				get["parameters"] = append(get["parameters"].([]interface{}), map[string]interface{}{"name": "X-Hand", "in": "header", "type": "string"})
+				get["responses"].(map[string]interface{})["404"] = map[string]interface{}{"description": "no such user"}
+				doc["paths"].(map[string]interface{})["/health"] = map[string]interface{}{"get": map[string]interface{}{"operationId": "health"}}
+				props := doc["definitions"].(map[string]interface{})["getUser-200"].(map[string]interface{})["properties"].(map[string]interface{})
+				props["nick"] = map[string]interface{}{"type": "string"}
+			},
+			want: map[string]string{
+				"$.paths['/users/{id}'].get.deprecated":                                            `[true]`,
+				"$.paths['/users/{id}'].get.parameters[?(@.name == 'X-Hand')].in":                  `["header"]`,
+				"$.paths['/users/{id}'].get.responses['404'].description":                          `["no such user"]`,
+				"$.paths['/health'].get.operationId":                                               `["health"]`,
+				"$.definitions['getUser-200'].properties.nick.type":                                `["string"]`,
+				"$.definitions['getUser-200'].properties.name.type":                                `["string"]`,
+				"$.paths['/users/{id}'].get.parameters[?(@.name == 'id')].required":                `[true]`,
+				"$.paths['/users/{id}'].get.parameters[?(@.name == 'X-Hand')].x-go2swag-generated": `[]`,
+			},
+		},
+		{
+			name: "stale generated parts are pruned",
+			edit: func(doc map[string]interface{}) {
+				get := op(doc, "/users/{id}", "get")
+				get["parameters"] = append(get["parameters"].([]interface{}), map[string]interface{}{"name": "old", "in": "query", generatedExt: "0123456789abcdef"})
+				get["responses"].(map[string]interface{})["410"] = map[string]interface{}{"description": "gone", generatedExt: "0123456789abcdef"}
+				doc["paths"].(map[string]interface{})["/old"] = map[string]interface{}{"get": map[string]interface{}{"operationId": "old", generatedExt: "0123456789abcdef"}}
+				doc["definitions"].(map[string]interface{})["old"] = map[string]interface{}{"type": "object", generatedExt: "0123456789abcdef"}
+			},
+			want: map[string]string{
+				"$.paths['/users/{id}'].get.parameters[?(@.name == 'old')]": `[]`,
+				"$.paths['/users/{id}'].get.responses['410']":               `[]`,
+				"$.paths['/old']":   `[]`,
+				"$.definitions.old": `[]`,
+			},
+		},
+	}
+
+	regenerate := func(t *testing.T, input *spec.Swagger) (interface{}, []string) {
+		diags := new(Diagnostics)
+		sw, err := build(scan, input, diags)
+		if err != nil {
+			t.Fatal(err)
+		}
+		doc, err := genericJSON(sw)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := []string{}
+		for _, d := range diags.List() {
+			got = append(got, strings.TrimPrefix(d.String(), "go2swag: "))
+		}
+		return doc, got
+	}
+
+	t.Run("regenerating changes nothing", func(t *testing.T) {
+		want, err := genericJSON(generated)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, diags := regenerate(t, generated)
+		if len(diags) > 0 {
+			t.Errorf("got diagnostics %q", diags)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got a different spec")
+		}
+	})
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			doc, err := genericJSON(generated)
+			if err != nil {
+				t.Fatal(err)
+			}
+			c.edit(doc.(map[string]interface{}))
+			input := new(spec.Swagger)
+			if err := fromGeneric(doc, input); err != nil {
+				t.Fatal(err)
+			}
+
+			got, diags := regenerate(t, input)
+			if len(diags) != len(c.diags) || len(diags) > 0 && !reflect.DeepEqual(diags, c.diags) {
+				t.Errorf("got diagnostics %q, want %q", diags, c.diags)
+			}
+			for expr, want := range c.want {
+				if s := selectJSON(t, got, expr); s != want {
+					t.Errorf("%s: got %s, want %s", expr, s, want)
+				}
+			}
+		})
+	}
+}
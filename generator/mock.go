@@ -0,0 +1,482 @@
+package generator
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-openapi/spec"
+)
+
+// MockStatusHeader and MockStatusQuery select the status a mock answers
+// with, the lowest declared 2xx status otherwise.
+const (
+	MockStatusHeader = "X-Mock-Status"
+	MockStatusQuery  = "mock_status"
+)
+
+// Mock is a handler answering the operations of the spec the way their
+// responses are declared. Requests are first checked against the parameters
+// and body schema of their operation and answered 400 with the errors found;
+// valid ones get the example of the selected response or, without one, data
+// synthesized from its schema, the same for every request, which follows
+// types, formats, enums and bounds but not patterns. Cross origin
+// requests are allowed, so that pages served elsewhere can use the mock.
+func Mock(sw *spec.Swagger) http.Handler {
+	return &mock{sw: sw, checker: newValueChecker(sw), ops: specOperations(sw)}
+}
+
+type mock struct {
+	sw      *spec.Swagger
+	checker *valueChecker
+	ops     []specOperation
+}
+
+// specOperation is an operation of the spec with its full path, split in
+// segments for matchPath, and its parameters.
+type specOperation struct {
+	method, path string
+	segs         []string
+	op           *spec.Operation
+	params       []spec.Parameter
+}
+
+// specOperations are the operations of the spec, those with the most
+// precise paths first so that they match before templates do.
+func specOperations(sw *spec.Swagger) []specOperation {
+	ops := []specOperation{}
+	if sw.Paths == nil {
+		return ops
+	}
+	basePath := strings.TrimSuffix(sw.BasePath, "/")
+	for p, item := range sw.Paths.Paths {
+		for _, o := range []struct {
+			method string
+			op     *spec.Operation
+		}{{"GET", item.Get}, {"POST", item.Post}, {"PUT", item.Put}, {"PATCH", item.Patch}, {"DELETE", item.Delete}, {"HEAD", item.Head}, {"OPTIONS", item.Options}} {
+			if o.op == nil {
+				continue
+			}
+			ops = append(ops, specOperation{
+				method: o.method,
+				path:   basePath + p,
+				segs:   splitPath(basePath + p),
+				op:     o.op,
+				params: operationParams(sw, item.Parameters, o.op.Parameters),
+			})
+		}
+	}
+	sort.SliceStable(ops, func(i, j int) bool {
+		if ops[i].path != ops[j].path {
+			return morePrecise(ops[i].path, ops[j].path)
+		}
+		return ops[i].method < ops[j].method
+	})
+	return ops
+}
+
+type mockError struct {
+	Operation string       `json:"operation,omitempty"`
+	Errors    []valueError `json:"errors"`
+}
+
+// operationParams are the parameters of an operation, with those of its path that
+// it does not override and references resolved.
+func operationParams(sw *spec.Swagger, pathParams, opParams []spec.Parameter) []spec.Parameter {
+	resolve := func(p spec.Parameter) spec.Parameter {
+		name := strings.TrimPrefix(p.Ref.String(), "#/parameters/")
+		if def, ok := sw.Parameters[name]; ok && name != "" {
+			return def
+		}
+		return p
+	}
+	params := []spec.Parameter{}
+	seen := map[string]bool{}
+	for _, p := range opParams {
+		p = resolve(p)
+		seen[p.In+" "+p.Name] = true
+		params = append(params, p)
+	}
+	for _, p := range pathParams {
+		p = resolve(p)
+		if !seen[p.In+" "+p.Name] {
+			params = append(params, p)
+		}
+	}
+	return params
+}
+
+func (self *mock) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if origin := r.Header.Get("Origin"); origin != "" {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Set("Vary", "Origin")
+	}
+
+	path := splitPath(r.URL.Path)
+	allowed := []string{}
+	seen := map[string]bool{}
+	for i := range self.ops {
+		o := &self.ops[i]
+		params, ok := matchPath(o.segs, path)
+		if !ok {
+			continue
+		}
+		if o.method != r.Method {
+			if !seen[o.method] {
+				seen[o.method] = true
+				allowed = append(allowed, o.method)
+			}
+			continue
+		}
+		self.serve(w, r, o, params)
+		return
+	}
+
+	if len(allowed) > 0 && r.Method == "OPTIONS" && r.Header.Get("Access-Control-Request-Method") != "" {
+		w.Header().Set("Access-Control-Allow-Methods", strings.Join(allowed, ", "))
+		w.Header().Set("Access-Control-Allow-Headers", r.Header.Get("Access-Control-Request-Headers"))
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if len(allowed) > 0 {
+		w.Header().Set("Allow", strings.Join(allowed, ", "))
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	http.NotFound(w, r)
+}
+
+func (self *mock) serve(w http.ResponseWriter, r *http.Request, o *specOperation, pathParams map[string]string) {
+	errs := self.checkRequest(r, o, pathParams)
+
+	status, resp := 0, (*spec.Response)(nil)
+	if len(errs) == 0 {
+		var msg string
+		status, resp, msg = self.response(r, o)
+		if msg != "" {
+			field := MockStatusHeader
+			if r.Header.Get(MockStatusHeader) == "" {
+				field = MockStatusQuery
+			}
+			errs = append(errs, valueError{Field: field, Message: msg})
+		}
+	}
+	if len(errs) > 0 {
+		writeMockJSON(w, http.StatusBadRequest, mockError{Operation: o.op.ID, Errors: errs})
+		return
+	}
+
+	if resp == nil {
+		w.WriteHeader(status)
+		return
+	}
+	seed := o.op.ID + " " + strconv.Itoa(status)
+	if o.op.ID == "" {
+		seed = o.method + " " + o.path + " " + strconv.Itoa(status)
+	}
+	for _, name := range sortedHeaderNames(resp.Headers) {
+		h := resp.Headers[name]
+		v := synthesize(self.sw, paramSchema(h.SimpleSchema, h.CommonValidations, h.Items), seed+" "+name, map[string]bool{})
+		w.Header().Set(name, fmt.Sprint(v))
+	}
+
+	body, ok := resp.Examples["application/json"]
+	if !ok && resp.Schema != nil {
+		body, ok = synthesize(self.sw, resp.Schema, seed, map[string]bool{}), true
+	}
+	if !ok || r.Method == "HEAD" || status == http.StatusNoContent {
+		w.WriteHeader(status)
+		return
+	}
+	writeMockJSON(w, status, body)
+}
+
+// checkRequest checks the parameters and body of the request.
+func (self *mock) checkRequest(r *http.Request, o *specOperation, pathParams map[string]string) []valueError {
+	errs := []valueError{}
+	query := r.URL.Query()
+	for _, p := range o.params {
+		if p.In == "body" {
+			self.checkBody(r, p, &errs)
+			continue
+		}
+
+		var raw []string
+		switch p.In {
+		case "path":
+			if v, ok := pathParams[p.Name]; ok {
+				raw = []string{v}
+			}
+		case "query":
+			raw = query[p.Name]
+		case "header":
+			raw = r.Header[http.CanonicalHeaderKey(p.Name)]
+		case "formData":
+			if err := r.ParseMultipartForm(32 << 20); err != nil && err != http.ErrNotMultipart {
+				errs = append(errs, valueError{Field: p.Name, Message: "cannot be parsed: " + err.Error()})
+				continue
+			}
+			raw = r.PostForm[p.Name]
+		}
+		if len(raw) == 0 {
+			if p.Required {
+				errs = append(errs, valueError{Field: p.Name, Message: "is required"})
+			}
+			continue
+		}
+		v, msg := paramValue(p.SimpleSchema, p.Items, raw)
+		if msg != "" {
+			errs = append(errs, valueError{Field: p.Name, Message: msg})
+			continue
+		}
+		self.checker.check(paramSchema(p.SimpleSchema, p.CommonValidations, p.Items), v, p.Name, &errs)
+	}
+	return errs
+}
+
+func (self *mock) checkBody(r *http.Request, p spec.Parameter, errs *[]valueError) {
+	var b []byte
+	if r.Body != nil {
+		var err error
+		if b, err = ioutil.ReadAll(r.Body); err != nil {
+			*errs = append(*errs, valueError{Field: "body", Message: "cannot be read: " + err.Error()})
+			return
+		}
+	}
+	if len(strings.TrimSpace(string(b))) == 0 {
+		if p.Required {
+			*errs = append(*errs, valueError{Field: "body", Message: "is required"})
+		}
+		return
+	}
+	var v interface{}
+	if err := json.Unmarshal(b, &v); err != nil {
+		*errs = append(*errs, valueError{Field: "body", Message: "cannot be parsed: " + err.Error()})
+		return
+	}
+	// fields are named like in the generated validation code, without
+	// a body prefix
+	self.checker.check(p.Schema, v, "", errs)
+	for i := range *errs {
+		if (*errs)[i].Field == "" {
+			(*errs)[i].Field = "body"
+		}
+	}
+}
+
+// response is the status and response selected by the request, or why
+// the selection is not declared.
+func (self *mock) response(r *http.Request, o *specOperation) (int, *spec.Response, string) {
+	var codes []int
+	var def *spec.Response
+	if o.op.Responses != nil {
+		for code := range o.op.Responses.StatusCodeResponses {
+			codes = append(codes, code)
+		}
+		def = o.op.Responses.Default
+	}
+	sort.Ints(codes)
+
+	selected := r.Header.Get(MockStatusHeader)
+	if selected == "" {
+		selected = r.URL.Query().Get(MockStatusQuery)
+	}
+	if selected != "" {
+		code, err := strconv.Atoi(selected)
+		if err != nil || !validStatusCode(selected) {
+			return 0, nil, "must be an HTTP status code"
+		}
+		if o.op.Responses != nil {
+			if resp, ok := o.op.Responses.StatusCodeResponses[code]; ok {
+				return code, &resp, ""
+			}
+		}
+		if def != nil {
+			return code, def, ""
+		}
+		declared := []string{}
+		for _, c := range codes {
+			declared = append(declared, strconv.Itoa(c))
+		}
+		return 0, nil, fmt.Sprintf("status %d is not declared, use one of %s", code, strings.Join(declared, ", "))
+	}
+
+	for _, code := range codes {
+		if code >= 200 && code < 300 {
+			resp := o.op.Responses.StatusCodeResponses[code]
+			return code, &resp, ""
+		}
+	}
+	if len(codes) > 0 {
+		resp := o.op.Responses.StatusCodeResponses[codes[0]]
+		return codes[0], &resp, ""
+	}
+	if def != nil {
+		return http.StatusOK, def, ""
+	}
+	return http.StatusNoContent, nil, ""
+}
+
+// synthesize is a value of the schema derived from the seed alone, so that
+// the same seed always gives the same data. Examples are used when given.
+func synthesize(sw *spec.Swagger, schema *spec.Schema, seed string, seen map[string]bool) interface{} {
+	for schema != nil && schema.Ref.String() != "" {
+		name := refName(schema)
+		def, ok := sw.Definitions[name]
+		if !ok || seen[name] {
+			return nil
+		}
+		seen[name] = true
+		defer delete(seen, name)
+		schema = &def
+	}
+	if schema == nil {
+		return nil
+	}
+	if schema.Example != nil {
+		return schema.Example
+	}
+	n := seedNumber(seed)
+	if len(schema.Enum) > 0 {
+		return schema.Enum[n%uint32(len(schema.Enum))]
+	}
+	// the property or header the value is for
+	name := seed[strings.LastIndexAny(seed, " .")+1:]
+	if i := strings.Index(name, "["); i >= 0 {
+		name = name[:i]
+	}
+
+	tpe := ""
+	if len(schema.Type) > 0 {
+		tpe = schema.Type[0]
+	}
+	switch {
+	case tpe == "object" || len(schema.Properties) > 0:
+		out := orderedMap{}
+		for _, prop := range propertyNames(schema) {
+			s := schema.Properties[prop]
+			out = append(out, orderedEntry{Key: prop, Value: synthesize(sw, &s, seed+"."+prop, seen)})
+		}
+		if schema.AdditionalProperties != nil && schema.AdditionalProperties.Schema != nil && len(out) == 0 {
+			out = append(out, orderedEntry{Key: "key", Value: synthesize(sw, schema.AdditionalProperties.Schema, seed+".key", seen)})
+		}
+		return out
+	case tpe == "array":
+		count := int64(1)
+		if schema.MinItems != nil && *schema.MinItems > count {
+			count = *schema.MinItems
+		}
+		if schema.MaxItems != nil && *schema.MaxItems < count {
+			count = *schema.MaxItems
+		}
+		list := []interface{}{}
+		for i := int64(0); i < count; i++ {
+			var item interface{}
+			if schema.Items != nil && schema.Items.Schema != nil {
+				item = synthesize(sw, schema.Items.Schema, seed+"["+strconv.FormatInt(i, 10)+"]", seen)
+			}
+			list = append(list, item)
+		}
+		return list
+	case tpe == "integer" || tpe == "number":
+		lo, hi := 1.0, 100.0
+		if schema.Minimum != nil {
+			lo = *schema.Minimum
+			if schema.ExclusiveMinimum {
+				lo++
+			}
+		}
+		if schema.Maximum != nil {
+			hi = *schema.Maximum
+			if schema.ExclusiveMaximum {
+				hi--
+			}
+		}
+		if schema.Minimum != nil && schema.Maximum == nil {
+			hi = lo + 99
+		}
+		if schema.Maximum != nil && schema.Minimum == nil {
+			lo = hi - 99
+		}
+		if hi < lo {
+			hi = lo
+		}
+		span := hi - lo + 1
+		if span > 1000 {
+			span = 1000
+		}
+		v := lo + float64(n%uint32(span))
+		if tpe == "number" && v+0.5 <= hi {
+			v += 0.5
+		}
+		return v
+	case tpe == "boolean":
+		return n%2 == 0
+	case tpe == "string":
+		return synthesizeString(schema, name, n)
+	}
+	return nil
+}
+
+func synthesizeString(schema *spec.Schema, name string, n uint32) string {
+	var s string
+	switch schema.Format {
+	case "date":
+		s = fmt.Sprintf("2024-%02d-%02d", n%12+1, n%28+1)
+	case "date-time":
+		s = fmt.Sprintf("2024-%02d-%02dT%02d:%02d:00Z", n%12+1, n%28+1, n%24, n%60)
+	case "email":
+		s = fmt.Sprintf("user%d@example.com", n%1000)
+	case "hostname":
+		s = fmt.Sprintf("host%d.example.com", n%1000)
+	case "ipv4":
+		s = fmt.Sprintf("192.0.2.%d", n%254+1)
+	case "ipv6":
+		s = fmt.Sprintf("2001:db8::%x", n%0xffff+1)
+	case "uri":
+		s = fmt.Sprintf("https://example.com/%s/%d", name, n%1000)
+	case "uuid":
+		h := fmt.Sprintf("%08x%08x%08x%08x", n, seedNumber(fmt.Sprint(n, 1)), seedNumber(fmt.Sprint(n, 2)), seedNumber(fmt.Sprint(n, 3)))
+		s = h[:8] + "-" + h[8:12] + "-4" + h[13:16] + "-8" + h[17:20] + "-" + h[20:32]
+	default:
+		if name == "" {
+			name = "string"
+		}
+		s = fmt.Sprintf("%s-%d", name, n%1000)
+	}
+	if schema.MaxLength != nil && int64(len(s)) > *schema.MaxLength {
+		s = s[:*schema.MaxLength]
+	}
+	if schema.MinLength != nil && int64(len(s)) < *schema.MinLength {
+		s += strings.Repeat("x", int(*schema.MinLength)-len(s))
+	}
+	return s
+}
+
+func seedNumber(seed string) uint32 {
+	h := fnv.New32a()
+	h.Write([]byte(seed))
+	return h.Sum32()
+}
+
+func sortedHeaderNames(headers map[string]spec.Header) []string {
+	names := []string{}
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func writeMockJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(v)
+}
@@ -0,0 +1,208 @@
+package generator
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/go-openapi/spec"
+)
+
+const mockSpec = `{
+  "swagger": "2.0",
+  "info": {"title": "mock", "version": "1"},
+  "basePath": "/v1",
+  "paths": {
+    "/items": {
+      "get": {
+        "operationId": "listItems",
+        "parameters": [
+          {"name": "limit", "in": "query", "type": "integer", "required": true, "minimum": 1, "maximum": 50},
+          {"name": "sort", "in": "query", "type": "string", "enum": ["name", "created"]},
+          {"name": "X-Tenant", "in": "header", "type": "string", "required": true}
+        ],
+        "responses": {
+          "200": {"description": "ok", "schema": {"type": "array", "minItems": 2, "items": {"$ref": "#/definitions/Item"}}},
+          "404": {"description": "gone"}
+        }
+      }
+    },
+    "/items/{id}": {
+      "get": {
+        "operationId": "getItem",
+        "parameters": [{"name": "id", "in": "path", "type": "integer", "required": true}],
+        "responses": {"200": {"description": "ok", "schema": {"$ref": "#/definitions/Item"}}}
+      }
+    }
+  },
+  "definitions": {
+    "Item": {
+      "type": "object",
+      "properties": {
+        "id": {"type": "integer", "minimum": 1, "maximum": 10},
+        "name": {"type": "string", "maxLength": 8},
+        "email": {"type": "string", "format": "email"},
+        "kind": {"type": "string", "enum": ["book", "film", "song"]},
+        "public": {"type": "boolean"}
+      }
+    }
+  }
+}`
+
+func testMockSpec(t *testing.T) *spec.Swagger {
+	t.Helper()
+	sw := new(spec.Swagger)
+	if err := json.Unmarshal([]byte(mockSpec), sw); err != nil {
+		t.Fatal(err)
+	}
+	return sw
+}
+
+func TestMockRequests(t *testing.T) {
+	generated, _ := testSpec(t, "testdata/api", "example.com/codegen/api")
+	specs := map[string]*spec.Swagger{"mock": testMockSpec(t), "api": generated}
+
+	cases := []struct {
+		name, spec, method, target, body string
+		header                           map[string]string
+		status                           int
+		// errors are field: message, for a 400
+		errors []string
+	}{
+		{
+			name: "valid", spec: "mock", method: "GET", target: "/v1/items?limit=5&sort=name",
+			header: map[string]string{"X-Tenant": "acme"}, status: 200,
+		},
+		{
+			name: "required query and header", spec: "mock", method: "GET", target: "/v1/items",
+			status: 400, errors: []string{"limit: is required", "X-Tenant: is required"},
+		},
+		{
+			name: "wrong types", spec: "mock", method: "GET", target: "/v1/items?limit=many",
+			header: map[string]string{"X-Tenant": "acme"}, status: 400, errors: []string{"limit: must be an integer"},
+		},
+		{
+			name: "bounds and enums", spec: "mock", method: "GET", target: "/v1/items?limit=51&sort=size",
+			header: map[string]string{"X-Tenant": "acme"}, status: 400, errors: []string{"limit: must be at most 50", "sort: must be one of name, created"},
+		},
+		{
+			name: "wrong path parameter type", spec: "mock", method: "GET", target: "/v1/items/abc",
+			status: 400, errors: []string{"id: must be an integer"},
+		},
+		{
+			name: "selected status", spec: "mock", method: "GET", target: "/v1/items/1?mock_status=404",
+			status: 400, errors: []string{"mock_status: status 404 is not declared, use one of 200"},
+		},
+		{
+			name: "valid body", spec: "api", method: "POST", target: "/users", body: `{"name": "bob", "age": 3, "tags": ["a"]}`,
+			status: 201,
+		},
+		{
+			name: "bad body", spec: "api", method: "POST", target: "/users", body: `{"name": "Bob", "age": 0, "email": "bob", "tags": ["a", "b", "c", "d"]}`,
+			status: 400, errors: []string{
+				"age: must be at least 1",
+				"email: must be an email address",
+				"name: must match ^[a-z]+$",
+				"tags: must have at most 3 items",
+			},
+		},
+		{
+			name: "body of the wrong type", spec: "api", method: "POST", target: "/users", body: `{"name": 7, "age": 1.5}`,
+			status: 400, errors: []string{"age: must be an integer", "name: must be a string"},
+		},
+		{
+			name: "missing required body field", spec: "api", method: "POST", target: "/users", body: `{}`,
+			status: 400, errors: []string{"name: is required"},
+		},
+		{
+			name: "body that is not JSON", spec: "api", method: "POST", target: "/users", body: `{"name":`,
+			status: 400, errors: []string{"body: cannot be parsed: unexpected end of JSON input"},
+		},
+		{
+			name: "unknown path", spec: "api", method: "GET", target: "/nope", status: 404,
+		},
+		{
+			name: "wrong method", spec: "api", method: "DELETE", target: "/users", status: 405,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			r := httptest.NewRequest(c.method, c.target, strings.NewReader(c.body))
+			for k, v := range c.header {
+				r.Header.Set(k, v)
+			}
+			w := httptest.NewRecorder()
+			Mock(specs[c.spec]).ServeHTTP(w, r)
+
+			if w.Code != c.status {
+				t.Fatalf("got status %d, want %d: %s", w.Code, c.status, w.Body)
+			}
+			if c.status != http.StatusBadRequest {
+				return
+			}
+			var got mockError
+			if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+				t.Fatal(err)
+			}
+			errs := []string{}
+			for _, e := range got.Errors {
+				errs = append(errs, e.Field+": "+e.Message)
+			}
+			if !reflect.DeepEqual(errs, c.errors) {
+				t.Errorf("got errors %q, want %q", errs, c.errors)
+			}
+		})
+	}
+}
+
+func TestMockSynthesis(t *testing.T) {
+	sw := testMockSpec(t)
+	item := spec.RefSchema("#/definitions/Item")
+
+	got, err := json.Marshal(synthesize(sw, item, "getItem 200", map[string]bool{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"email":"user784@example.com","id":6,"kind":"song","name":"name-131","public":false}`
+	if string(got) != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+
+	// the data follows the schema
+	var v interface{}
+	json.Unmarshal(got, &v)
+	errs := []valueError{}
+	newValueChecker(sw).check(item, v, "", &errs)
+	if len(errs) > 0 {
+		t.Errorf("synthesized data does not match its schema: %v", errs)
+	}
+
+	// another seed gives other data
+	other, _ := json.Marshal(synthesize(sw, item, "listItems 200", map[string]bool{}))
+	if string(other) == string(got) {
+		t.Errorf("got the same data for another seed")
+	}
+
+	// and the mock answers every request the same
+	bodies := []string{}
+	for i := 0; i < 2; i++ {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "/v1/items?limit=1", nil)
+		r.Header.Set("X-Tenant", "acme")
+		Mock(sw).ServeHTTP(w, r)
+		if w.Code != http.StatusOK {
+			t.Fatalf("got status %d: %s", w.Code, w.Body)
+		}
+		bodies = append(bodies, w.Body.String())
+	}
+	if bodies[0] != bodies[1] {
+		t.Errorf("got different bodies %s and %s", bodies[0], bodies[1])
+	}
+	var list []interface{}
+	if err := json.Unmarshal([]byte(bodies[0]), &list); err != nil || len(list) != 2 {
+		t.Errorf("got %s, want minItems items", bodies[0])
+	}
+}
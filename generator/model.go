@@ -0,0 +1,129 @@
+package generator
+
+import (
+	"go/token"
+	"sort"
+)
+
+// ModelVersion is bumped whenever a field of the model changes meaning or
+// goes away; new fields may appear without a bump.
+const ModelVersion = "1"
+
+// Model is the scan result as plain data, independent of any swagger
+// rendering, for tools that want go2swag's view of the routes.
+type Model struct {
+	// Version is ModelVersion at the time of writing.
+	Version string `json:"version"`
+	// Groups maps package paths to their swag:group.
+	Groups map[string]ModelGroup `json:"groups,omitempty"`
+	// Routes are in package, file and position order.
+	Routes []ModelRoute `json:"routes"`
+	// Unbound are swag:req and swag:ans types naming no known route.
+	Unbound []ModelType `json:"unbound,omitempty"`
+}
+
+type ModelGroup struct {
+	Prefix string   `json:"prefix,omitempty"`
+	Tags   []string `json:"tags,omitempty"`
+}
+
+type ModelRoute struct {
+	ID     string `json:"id"`
+	Method string `json:"method"`
+	// Path is as declared in swag:route, FullPath has the group prefixes applied.
+	Path     string   `json:"path"`
+	FullPath string   `json:"fullPath"`
+	Tags     []string `json:"tags,omitempty"`
+	// Package is the import path of the package declaring the route.
+	Package  string        `json:"package"`
+	Position ModelPosition `json:"position"`
+	// Comment is the route comment without the swag:route line.
+	Comment   string      `json:"comment,omitempty"`
+	Request   *ModelType  `json:"request,omitempty"`
+	Responses []ModelType `json:"responses,omitempty"`
+}
+
+// ModelType is a Go type bound to a route by swag:req or swag:ans.
+type ModelType struct {
+	Route string `json:"route"`
+	// Status is the response code of swag:ans, zero for requests.
+	Status   int           `json:"status,omitempty"`
+	Package  string        `json:"package"`
+	Name     string        `json:"name"`
+	Position ModelPosition `json:"position"`
+}
+
+type ModelPosition struct {
+	File   string `json:"file"`
+	Line   int    `json:"line"`
+	Column int    `json:"column"`
+}
+
+// Model returns the scan result as a Model.
+func (self *Scanner) Model() *Model {
+	m := Model{
+		Version: ModelVersion,
+		Routes:  []ModelRoute{},
+	}
+
+	if len(self.groups) > 0 {
+		m.Groups = map[string]ModelGroup{}
+		for pkg, g := range self.groups {
+			m.Groups[pkg] = ModelGroup{Prefix: g.Prefix, Tags: g.Tags}
+		}
+	}
+
+	for _, r := range self.Routes() {
+		route := ModelRoute{
+			ID:       r.ID,
+			Method:   r.Method,
+			Path:     r.Path,
+			FullPath: self.RoutePath(r),
+			Tags:     self.RouteTags(r),
+			Package:  r.Pkg,
+			Position: modelPosition(r.Pos),
+			Comment:  r.Comment(),
+		}
+		if req, ok := self.reqs[r.ID]; ok {
+			t := modelType(req, 0)
+			route.Request = &t
+		}
+		m.Routes = append(m.Routes, route)
+	}
+
+	index := map[string]int{}
+	for i, r := range m.Routes {
+		index[r.ID] = i
+	}
+	for _, ans := range self.Responses() {
+		if i, ok := index[ans.ID]; ok {
+			m.Routes[i].Responses = append(m.Routes[i].Responses, modelType(ans, ans.Code))
+		} else {
+			m.Unbound = append(m.Unbound, modelType(ans, ans.Code))
+		}
+	}
+	for _, r := range m.Routes {
+		sort.SliceStable(r.Responses, func(i, j int) bool { return r.Responses[i].Status < r.Responses[j].Status })
+	}
+	for _, req := range self.Requests() {
+		if _, ok := index[req.ID]; !ok {
+			m.Unbound = append(m.Unbound, modelType(req, 0))
+		}
+	}
+
+	return &m
+}
+
+func modelType(d *Decl, status int) ModelType {
+	return ModelType{
+		Route:    d.ID,
+		Status:   status,
+		Package:  d.PkgPath,
+		Name:     d.TypeName,
+		Position: modelPosition(d.Pos()),
+	}
+}
+
+func modelPosition(pos token.Position) ModelPosition {
+	return ModelPosition{File: pos.Filename, Line: pos.Line, Column: pos.Column}
+}
@@ -0,0 +1,314 @@
+package generator
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-openapi/spec"
+	"gopkg.in/yaml.v2"
+)
+
+type orderKind int
+
+const (
+	kindAny orderKind = iota
+	kindRoot
+	kindInfo
+	kindPaths
+	kindPathItem
+	kindOperation
+	kindParameter
+	kindResponses
+	kindResponse
+	kindSchema
+	kindSchemaMap
+	kindParameterMap
+	kindResponseMap
+	kindProperties
+)
+
+// Conventional key orders, anything not listed follows alphabetically.
+var keyOrders = map[orderKind][]string{
+	kindRoot:      {"swagger", "info", "host", "basePath", "schemes", "consumes", "produces", "paths", "definitions", "parameters", "responses", "securityDefinitions", "security", "tags", "externalDocs"},
+	kindInfo:      {"title", "description", "termsOfService", "contact", "license", "version"},
+	kindPathItem:  {"$ref", "get", "put", "post", "delete", "options", "head", "patch", "parameters"},
+	kindOperation: {"tags", "summary", "description", "externalDocs", "operationId", "consumes", "produces", "parameters", "responses", "schemes", "deprecated", "security"},
+	kindParameter: {"name", "in", "description", "required", "type", "format", "schema", "items", "collectionFormat", "default", "enum"},
+	kindResponse:  {"description", "schema", "headers", "examples"},
+	kindSchema:    {"$ref", "type", "format", "title", "description", "required", "enum", "default", "example", "properties", "additionalProperties", "items", "allOf"},
+}
+
+type orderedEntry struct {
+	Key   string
+	Value interface{}
+}
+
+// orderedMap keeps its keys in insertion order when marshalled to JSON or YAML.
+type orderedMap []orderedEntry
+
+func (self orderedMap) MarshalYAML() (interface{}, error) {
+	ms := yaml.MapSlice{}
+	for _, e := range self {
+		ms = append(ms, yaml.MapItem{Key: e.Key, Value: e.Value})
+	}
+	return ms, nil
+}
+
+func (self orderedMap) MarshalJSON() ([]byte, error) {
+	buf := bytes.NewBufferString("{")
+	for i, e := range self {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		k, err := json.Marshal(e.Key)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(k)
+		buf.WriteByte(':')
+		v, err := json.Marshal(e.Value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(v)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// ordered converts the JSON form of v into nested orderedMaps whose key
+// order is stable and follows the layout people expect in a swagger file.
+func ordered(v interface{}) (interface{}, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var doc interface{}
+	if err := dec.Decode(&doc); err != nil {
+		return nil, err
+	}
+	return orderValue(doc, kindRoot), nil
+}
+
+func orderValue(v interface{}, kind orderKind) interface{} {
+	switch tv := v.(type) {
+	case map[string]interface{}:
+		return orderObject(tv, kind)
+	case []interface{}:
+		out := make([]interface{}, len(tv))
+		for i, item := range tv {
+			out[i] = orderValue(item, kind)
+		}
+		return out
+	case json.Number:
+		if i, err := tv.Int64(); err == nil {
+			return i
+		}
+		if f, err := tv.Float64(); err == nil {
+			return f
+		}
+		return tv.String()
+	}
+	return v
+}
+
+// responseOrder ranks a key of responses: status codes first, by their
+// number, then other keys, then default.
+func responseOrder(key string) (int, int) {
+	if key == "default" {
+		return 2, 0
+	}
+	if code, err := strconv.Atoi(key); err == nil {
+		return 0, code
+	}
+	return 1, 0
+}
+
+func orderObject(obj map[string]interface{}, kind orderKind) orderedMap {
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+
+	switch kind {
+	case kindResponses:
+		sort.Slice(keys, func(i, j int) bool {
+			// status codes numerically, then extensions, "default" last
+			ri, ni := responseOrder(keys[i])
+			rj, nj := responseOrder(keys[j])
+			if ri != rj {
+				return ri < rj
+			}
+			if ni != nj {
+				return ni < nj
+			}
+			return keys[i] < keys[j]
+		})
+	case kindProperties:
+		sort.Slice(keys, func(i, j int) bool {
+			oi, iok := propertyOrder(obj[keys[i]])
+			oj, jok := propertyOrder(obj[keys[j]])
+			if iok != jok {
+				return iok
+			}
+			if iok && oi != oj {
+				return oi < oj
+			}
+			return keys[i] < keys[j]
+		})
+	default:
+		rank := map[string]int{}
+		for i, k := range keyOrders[kind] {
+			rank[k] = i + 1
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			ri, rj := rank[keys[i]], rank[keys[j]]
+			if ri != 0 && rj != 0 {
+				return ri < rj
+			}
+			if ri != 0 || rj != 0 {
+				return ri != 0
+			}
+			return keys[i] < keys[j]
+		})
+	}
+
+	out := make(orderedMap, 0, len(keys))
+	for _, k := range keys {
+		out = append(out, orderedEntry{Key: k, Value: orderValue(obj[k], childKind(kind, k))})
+	}
+	return out
+}
+
+func childKind(parent orderKind, key string) orderKind {
+	if strings.HasPrefix(key, "x-") {
+		return kindAny
+	}
+	switch parent {
+	case kindRoot:
+		switch key {
+		case "info":
+			return kindInfo
+		case "paths":
+			return kindPaths
+		case "definitions":
+			return kindSchemaMap
+		case "parameters":
+			return kindParameterMap
+		case "responses":
+			return kindResponseMap
+		}
+	case kindParameterMap:
+		return kindParameter
+	case kindResponseMap:
+		return kindResponse
+	case kindPaths:
+		return kindPathItem
+	case kindPathItem:
+		if key == "parameters" {
+			return kindParameter
+		}
+		return kindOperation
+	case kindOperation:
+		switch key {
+		case "parameters":
+			return kindParameter
+		case "responses":
+			return kindResponses
+		}
+	case kindParameter:
+		if key == "schema" || key == "items" {
+			return kindSchema
+		}
+	case kindResponses:
+		return kindResponse
+	case kindResponse:
+		if key == "schema" {
+			return kindSchema
+		}
+	case kindSchemaMap:
+		return kindSchema
+	case kindSchema:
+		switch key {
+		case "properties":
+			return kindProperties
+		case "items", "additionalProperties", "allOf":
+			return kindSchema
+		case "example", "default", "enum":
+			return kindAny
+		}
+	case kindProperties:
+		return kindSchema
+	}
+	return kindAny
+}
+
+func propertyOrder(v interface{}) (int64, bool) {
+	obj, ok := v.(map[string]interface{})
+	if !ok {
+		return 0, false
+	}
+	switch o := obj["x-order"].(type) {
+	case json.Number:
+		i, err := o.Int64()
+		return i, err == nil
+	case string:
+		i, err := strconv.ParseInt(o, 10, 64)
+		return i, err == nil
+	}
+	return 0, false
+}
+
+// Marshal encodes the spec as YAML or JSON with a stable, conventional key order.
+func Marshal(sw *spec.Swagger, asYAML, pretty bool) ([]byte, error) {
+	doc, err := ordered(sw)
+	if err != nil {
+		return nil, err
+	}
+	if asYAML {
+		return yaml.Marshal(doc)
+	}
+	if pretty {
+		return json.MarshalIndent(doc, "", "  ")
+	}
+	return json.Marshal(doc)
+}
+
+// Save writes the spec to output: YAML when the name ends in yml or yaml,
+// a Go file embedding it when it ends in .go, a reference document when it
+// ends in .md or .html, JSON otherwise, or to stdout when output is empty.
+func Save(sw *spec.Swagger, pretty bool, output string) error {
+	b, err := Encode(sw, pretty, output)
+	if err != nil {
+		return err
+	}
+
+	if output == "" {
+		fmt.Println(string(b))
+		return nil
+	}
+
+	return ioutil.WriteFile(output, b, 0644)
+}
+
+// Encode is what Save writes to output.
+func Encode(sw *spec.Swagger, pretty bool, output string) ([]byte, error) {
+	if strings.HasSuffix(output, ".go") {
+		return GoSource(sw, PackageName(filepath.Dir(output)))
+	}
+	if strings.HasSuffix(output, ".md") {
+		return Markdown(sw)
+	}
+	if strings.HasSuffix(output, ".html") || strings.HasSuffix(output, ".htm") {
+		return HTML(sw)
+	}
+	return Marshal(sw, strings.HasSuffix(output, "yml") || strings.HasSuffix(output, "yaml"), pretty)
+}
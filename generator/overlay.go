@@ -0,0 +1,511 @@
+package generator
+
+import (
+	"fmt"
+	"go/token"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-openapi/spec"
+	"gopkg.in/yaml.v2"
+)
+
+// overlay is an OpenAPI Overlay document: a list of JSONPath targets with
+// update or remove actions applied to the generated spec.
+type overlay struct {
+	Overlay string `json:"overlay"`
+	Info    struct {
+		Title   string `json:"title"`
+		Version string `json:"version"`
+	} `json:"info"`
+	Actions []overlayAction `json:"actions"`
+}
+
+type overlayAction struct {
+	Target      string      `json:"target"`
+	Description string      `json:"description"`
+	Update      interface{} `json:"update"`
+	Remove      bool        `json:"remove"`
+}
+
+func applyOverlays(sw *spec.Swagger, files []string, diags *Diagnostics) *spec.Swagger {
+	if len(files) == 0 {
+		return sw
+	}
+
+	doc, err := genericJSON(sw)
+	if err != nil {
+		diags.Errorf(token.Position{}, CodeOverlay, "%v", err)
+		return sw
+	}
+
+	for _, file := range files {
+		ov, err := readOverlay(file)
+		if err != nil {
+			diags.Errorf(token.Position{Filename: file}, CodeOverlay, "%v", err)
+			continue
+		}
+		for i, action := range ov.Actions {
+			pos := token.Position{Filename: file}
+			path, err := parseJSONPath(action.Target)
+			if err != nil {
+				diags.Errorf(pos, CodeOverlay, "action %d: %v", i, err)
+				continue
+			}
+
+			root := &jsonNode{doc: &doc}
+			nodes := path.eval(root)
+			if len(nodes) == 0 {
+				diags.Warnf(pos, CodeOverlay, "action %d: target %s matched nothing", i, action.Target)
+				continue
+			}
+
+			if action.Remove {
+				// delete from the back so array indexes stay valid
+				sort.SliceStable(nodes, func(i, j int) bool { return nodes[i].idx > nodes[j].idx })
+				for _, n := range nodes {
+					n.remove()
+				}
+				continue
+			}
+			if action.Update != nil {
+				for _, n := range nodes {
+					n.update(action.Update)
+				}
+			}
+		}
+	}
+
+	out := new(spec.Swagger)
+	if err := fromGeneric(doc, out); err != nil {
+		diags.Errorf(token.Position{}, CodeOverlay, "%v", err)
+		return sw
+	}
+	return out
+}
+
+func readOverlay(file string) (*overlay, error) {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var raw interface{}
+	if err := yaml.Unmarshal(b, &raw); err != nil {
+		return nil, err
+	}
+	ov := new(overlay)
+	if err := fromGeneric(yamlToJSON(raw), ov); err != nil {
+		return nil, err
+	}
+	if ov.Overlay == "" {
+		return nil, fmt.Errorf("not an overlay document, missing the overlay version")
+	}
+	return ov, nil
+}
+
+// yamlToJSON turns the map[interface{}]interface{} produced by yaml.v2
+// into values encoding/json understands.
+func yamlToJSON(v interface{}) interface{} {
+	switch tv := v.(type) {
+	case map[interface{}]interface{}:
+		out := map[string]interface{}{}
+		for k, item := range tv {
+			out[fmt.Sprint(k)] = yamlToJSON(item)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(tv))
+		for i, item := range tv {
+			out[i] = yamlToJSON(item)
+		}
+		return out
+	}
+	return v
+}
+
+// jsonNode is a located value in a generic JSON document. Values are read
+// through the parent chain so edits made through one node are visible to
+// the others.
+type jsonNode struct {
+	doc    *interface{}
+	parent *jsonNode
+	key    string
+	idx    int
+}
+
+func (self *jsonNode) get() interface{} {
+	if self.parent == nil {
+		return *self.doc
+	}
+	switch pv := self.parent.get().(type) {
+	case map[string]interface{}:
+		return pv[self.key]
+	case []interface{}:
+		if self.idx < len(pv) {
+			return pv[self.idx]
+		}
+	}
+	return nil
+}
+
+func (self *jsonNode) set(v interface{}) {
+	if self.parent == nil {
+		*self.doc = v
+		return
+	}
+	switch pv := self.parent.get().(type) {
+	case map[string]interface{}:
+		pv[self.key] = v
+	case []interface{}:
+		if self.idx < len(pv) {
+			pv[self.idx] = v
+		}
+	}
+}
+
+func (self *jsonNode) remove() {
+	if self.parent == nil {
+		*self.doc = nil
+		return
+	}
+	switch pv := self.parent.get().(type) {
+	case map[string]interface{}:
+		delete(pv, self.key)
+	case []interface{}:
+		if self.idx < len(pv) {
+			self.parent.set(append(pv[:self.idx:self.idx], pv[self.idx+1:]...))
+		}
+	}
+}
+
+func (self *jsonNode) update(v interface{}) {
+	switch cur := self.get().(type) {
+	case map[string]interface{}:
+		if obj, ok := v.(map[string]interface{}); ok {
+			mergeUpdate(cur, obj)
+			return
+		}
+	case []interface{}:
+		self.set(append(cur, v))
+		return
+	}
+	self.set(v)
+}
+
+func mergeUpdate(dst, src map[string]interface{}) {
+	for k, sv := range src {
+		dobj, dok := dst[k].(map[string]interface{})
+		sobj, sok := sv.(map[string]interface{})
+		if dok && sok {
+			mergeUpdate(dobj, sobj)
+			continue
+		}
+		dst[k] = sv
+	}
+}
+
+func (self *jsonNode) children() []*jsonNode {
+	out := []*jsonNode{}
+	switch v := self.get().(type) {
+	case map[string]interface{}:
+		keys := []string{}
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			out = append(out, &jsonNode{doc: self.doc, parent: self, key: k, idx: -1})
+		}
+	case []interface{}:
+		for i := range v {
+			out = append(out, &jsonNode{doc: self.doc, parent: self, idx: i})
+		}
+	}
+	return out
+}
+
+func (self *jsonNode) descendants() []*jsonNode {
+	out := []*jsonNode{self}
+	for _, c := range self.children() {
+		out = append(out, c.descendants()...)
+	}
+	return out
+}
+
+type jsonPathSeg struct {
+	recursive bool
+	wildcard  bool
+	names     []string
+	index     *int
+	filter    *jsonPathFilter
+}
+
+type jsonPathFilter struct {
+	path  []string
+	op    string
+	value interface{}
+}
+
+type jsonPath []jsonPathSeg
+
+// parseJSONPath understands the subset of JSONPath used by overlays: child
+// names, wildcards, indexes, recursive descent and simple comparisons in
+// filters such as [?(@.in == 'query')].
+func parseJSONPath(expr string) (jsonPath, error) {
+	expr = strings.TrimSpace(expr)
+	if !strings.HasPrefix(expr, "$") {
+		return nil, fmt.Errorf("jsonpath %q must start with $", expr)
+	}
+
+	path := jsonPath{}
+	rest := expr[1:]
+	for len(rest) > 0 {
+		seg := jsonPathSeg{}
+		switch {
+		case strings.HasPrefix(rest, ".."):
+			seg.recursive = true
+			rest = rest[2:]
+			if strings.HasPrefix(rest, "[") {
+				break
+			}
+			name, remaining := readName(rest)
+			rest = remaining
+			if name == "*" {
+				seg.wildcard = true
+			} else {
+				seg.names = []string{name}
+			}
+			path = append(path, seg)
+			continue
+		case strings.HasPrefix(rest, "."):
+			name, remaining := readName(rest[1:])
+			if name == "" {
+				return nil, fmt.Errorf("jsonpath %q: empty name", expr)
+			}
+			rest = remaining
+			if name == "*" {
+				seg.wildcard = true
+			} else {
+				seg.names = []string{name}
+			}
+			path = append(path, seg)
+			continue
+		}
+
+		if !strings.HasPrefix(rest, "[") {
+			return nil, fmt.Errorf("jsonpath %q: unexpected %q", expr, rest)
+		}
+		end := matchingBracket(rest)
+		if end < 0 {
+			return nil, fmt.Errorf("jsonpath %q: unbalanced brackets", expr)
+		}
+		inner := strings.TrimSpace(rest[1:end])
+		rest = rest[end+1:]
+
+		switch {
+		case inner == "*":
+			seg.wildcard = true
+		case strings.HasPrefix(inner, "?"):
+			f, err := parseFilter(strings.TrimSpace(inner[1:]))
+			if err != nil {
+				return nil, fmt.Errorf("jsonpath %q: %v", expr, err)
+			}
+			seg.filter = f
+		case strings.HasPrefix(inner, "'") || strings.HasPrefix(inner, "\""):
+			for _, part := range splitOutsideQuotes(inner, ',') {
+				name, err := unquoteJSONPath(strings.TrimSpace(part))
+				if err != nil {
+					return nil, fmt.Errorf("jsonpath %q: %v", expr, err)
+				}
+				seg.names = append(seg.names, name)
+			}
+		default:
+			i, err := strconv.Atoi(inner)
+			if err != nil {
+				return nil, fmt.Errorf("jsonpath %q: bad index %q", expr, inner)
+			}
+			seg.index = &i
+		}
+		path = append(path, seg)
+	}
+	return path, nil
+}
+
+func readName(s string) (string, string) {
+	i := strings.IndexAny(s, ".[")
+	if i < 0 {
+		return s, ""
+	}
+	return s[:i], s[i:]
+}
+
+func matchingBracket(s string) int {
+	depth := 0
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '[':
+			depth++
+		case c == ']':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+func splitOutsideQuotes(s string, sep byte) []string {
+	parts := []string{}
+	var quote byte
+	start := 0
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == sep:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+func unquoteJSONPath(s string) (string, error) {
+	if len(s) >= 2 && (s[0] == '\'' || s[0] == '"') && s[len(s)-1] == s[0] {
+		return s[1 : len(s)-1], nil
+	}
+	return "", fmt.Errorf("bad quoted name %s", s)
+}
+
+func parseFilter(s string) (*jsonPathFilter, error) {
+	if strings.HasPrefix(s, "(") && strings.HasSuffix(s, ")") {
+		s = strings.TrimSpace(s[1 : len(s)-1])
+	}
+
+	f := &jsonPathFilter{}
+	lhs := s
+	for _, op := range []string{"==", "!="} {
+		if i := strings.Index(s, op); i >= 0 {
+			f.op = op
+			lhs = strings.TrimSpace(s[:i])
+			rhs := strings.TrimSpace(s[i+len(op):])
+			if v, err := unquoteJSONPath(rhs); err == nil {
+				f.value = v
+			} else if rhs == "true" || rhs == "false" {
+				f.value = rhs == "true"
+			} else if n, err := strconv.ParseFloat(rhs, 64); err == nil {
+				f.value = n
+			} else {
+				return nil, fmt.Errorf("unsupported filter value %s", rhs)
+			}
+			break
+		}
+	}
+
+	if lhs != "@" && !strings.HasPrefix(lhs, "@.") {
+		return nil, fmt.Errorf("unsupported filter %s", s)
+	}
+	if lhs != "@" {
+		f.path = strings.Split(lhs[2:], ".")
+	}
+	return f, nil
+}
+
+func (self *jsonPathFilter) match(v interface{}) bool {
+	for _, p := range self.path {
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		if v, ok = obj[p]; !ok {
+			return false
+		}
+	}
+	if self.op == "" {
+		return true
+	}
+
+	equal := fmt.Sprint(normalizeNumber(v)) == fmt.Sprint(normalizeNumber(self.value))
+	if self.op == "==" {
+		return equal
+	}
+	return !equal
+}
+
+func normalizeNumber(v interface{}) interface{} {
+	if n, ok := v.(interface{ Float64() (float64, error) }); ok {
+		if f, err := n.Float64(); err == nil {
+			return f
+		}
+	}
+	return v
+}
+
+func (self jsonPath) eval(root *jsonNode) []*jsonNode {
+	nodes := []*jsonNode{root}
+	for _, seg := range self {
+		if seg.recursive {
+			all := []*jsonNode{}
+			for _, n := range nodes {
+				all = append(all, n.descendants()...)
+			}
+			nodes = all
+		}
+
+		next := []*jsonNode{}
+		for _, n := range nodes {
+			next = append(next, seg.apply(n)...)
+		}
+		nodes = next
+	}
+	return nodes
+}
+
+func (self jsonPathSeg) apply(n *jsonNode) []*jsonNode {
+	out := []*jsonNode{}
+	switch {
+	case self.wildcard:
+		return n.children()
+	case self.filter != nil:
+		for _, c := range n.children() {
+			if self.filter.match(c.get()) {
+				out = append(out, c)
+			}
+		}
+	case self.index != nil:
+		if arr, ok := n.get().([]interface{}); ok {
+			i := *self.index
+			if i < 0 {
+				i += len(arr)
+			}
+			if i >= 0 && i < len(arr) {
+				out = append(out, &jsonNode{doc: n.doc, parent: n, idx: i})
+			}
+		}
+	default:
+		if obj, ok := n.get().(map[string]interface{}); ok {
+			for _, name := range self.names {
+				if _, exists := obj[name]; exists {
+					out = append(out, &jsonNode{doc: n.doc, parent: n, key: name, idx: -1})
+				}
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,239 @@
+package generator
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/go-openapi/spec"
+)
+
+const overlayTestDoc = `{
+	"info": {"title": "API", "version": "1.0"},
+	"paths": {
+		"/users": {
+			"get": {
+				"tags": ["users"],
+				"parameters": [
+					{"name": "q", "in": "query"},
+					{"name": "X-Key", "in": "header"},
+					{"name": "page", "in": "query", "required": true}
+				]
+			}
+		},
+		"/users/{id}": {
+			"get": {"parameters": [{"name": "id", "in": "path", "x-n": 1}]}
+		}
+	}
+}`
+
+func TestJSONPath(t *testing.T) {
+	cases := []struct {
+		expr string
+		want string
+	}{
+		{"$", ""},
+		{"$.info.title", `["API"]`},
+		{"$['info']['title', 'version']", `["API","1.0"]`},
+		{`$["info"].version`, `["1.0"]`},
+		{"$.info.missing", `[]`},
+		{"$.info.*", `["API","1.0"]`},
+		{"$.info[*]", `["API","1.0"]`},
+		{"$.paths['/users'].get.parameters[0].name", `["q"]`},
+		{"$.paths['/users'].get.parameters[-1].name", `["page"]`},
+		{"$.paths['/users'].get.parameters[3]", `[]`},
+		{"$.paths['/users'].get.parameters[?(@.in == 'query')].name", `["q","page"]`},
+		{"$.paths['/users'].get.parameters[?(@.in != 'query')].name", `["X-Key"]`},
+		{"$.paths['/users'].get.parameters[?(@.required)].name", `["page"]`},
+		{"$.paths['/users'].get.parameters[?(@.required == true)].name", `["page"]`},
+		{"$..parameters[?(@.x-n == 1)].name", `["id"]`},
+		{"$..name", `["q","X-Key","page","id"]`},
+		{"$..[?(@.in == 'path')].name", `["id"]`},
+		{"$.paths.*.get.tags[0]", `["users"]`},
+	}
+
+	for _, c := range cases {
+		var doc interface{}
+		if err := json.Unmarshal([]byte(overlayTestDoc), &doc); err != nil {
+			t.Fatal(err)
+		}
+		path, err := parseJSONPath(c.expr)
+		if err != nil {
+			t.Errorf("%s: %v", c.expr, err)
+			continue
+		}
+		values := []interface{}{}
+		for _, n := range path.eval(&jsonNode{doc: &doc}) {
+			values = append(values, n.get())
+		}
+		if c.want == "" {
+			if len(values) != 1 || !reflect.DeepEqual(values[0], doc) {
+				t.Errorf("%s: got %v, want the document", c.expr, values)
+			}
+			continue
+		}
+		b, _ := json.Marshal(values)
+		if string(b) != c.want {
+			t.Errorf("%s: got %s, want %s", c.expr, b, c.want)
+		}
+	}
+}
+
+func TestJSONPathErrors(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"info.title",
+		"$.",
+		"$.info.",
+		"$info",
+		"$[",
+		"$['info'",
+		"$[abc]",
+		"$['info]",
+		"$[?(foo)]",
+		"$[?(@.in == query)]",
+	} {
+		if _, err := parseJSONPath(expr); err == nil {
+			t.Errorf("%q: got no error", expr)
+		}
+	}
+}
+
+func TestApplyOverlays(t *testing.T) {
+	cases := []struct {
+		name    string
+		overlay string
+		// want are JSONPath expressions and the values they select after
+		// the overlay is applied.
+		want  map[string]string
+		diags []string
+	}{
+		{
+			name: "update merges objects",
+			overlay: `
+overlay: 1.0.0
+actions:
+- target: $.info
+  update:
+    description: Users
+    title: Users API
+`,
+			want: map[string]string{"$.info": `[{"description":"Users","title":"Users API","version":"1.0"}]`},
+		},
+		{
+			name: "update appends to arrays and replaces values",
+			overlay: `
+overlay: 1.0.0
+actions:
+- target: $.paths['/users'].get.tags
+  update: admin
+- target: $..parameters[?(@.name == 'q')].in
+  update: header
+`,
+			want: map[string]string{
+				"$.paths['/users'].get.tags":                                 `[["users","admin"]]`,
+				"$.paths['/users'].get.parameters[?(@.in == 'header')].name": `["q","X-Key"]`,
+			},
+		},
+		{
+			name: "remove",
+			overlay: `
+overlay: 1.0.0
+actions:
+- target: $.paths['/users'].get.parameters[?(@.in == 'query')]
+  remove: true
+- target: $.paths['/users/{id}']
+  remove: true
+`,
+			want: map[string]string{
+				"$.paths['/users'].get.parameters[*].name": `["X-Key"]`,
+				"$.paths.*": `[{"get":{"parameters":[{"in":"header","name":"X-Key"}],"tags":["users"]}}]`,
+			},
+		},
+		{
+			name: "unmatched and invalid targets",
+			overlay: `
+overlay: 1.0.0
+actions:
+- target: $.paths['/none']
+  remove: true
+- target: paths
+  remove: true
+- target: $.info.title
+  update: Still applied
+`,
+			want: map[string]string{"$.info.title": `["Still applied"]`},
+			diags: []string{
+				"warning: action 0: target $.paths['/none'] matched nothing [GS303]",
+				`error: action 1: jsonpath "paths" must start with $ [GS303]`,
+			},
+		},
+		{
+			name:    "not an overlay",
+			overlay: "actions: []\n",
+			want:    map[string]string{"$.info.title": `["API"]`},
+			diags:   []string{"error: not an overlay document, missing the overlay version [GS303]"},
+		},
+	}
+
+	dir, err := ioutil.TempDir("", "go2swag-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for i, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			sw := new(spec.Swagger)
+			if err := json.Unmarshal([]byte(overlayTestDoc), sw); err != nil {
+				t.Fatal(err)
+			}
+			file := filepath.Join(dir, strings.Repeat("o", i+1)+".yaml")
+			if err := ioutil.WriteFile(file, []byte(c.overlay), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			diags := new(Diagnostics)
+			out := applyOverlays(sw, []string{file}, diags)
+			got := []string{}
+			for _, d := range diags.List() {
+				got = append(got, strings.TrimPrefix(d.String(), file+": "))
+			}
+			if len(got) != len(c.diags) || len(got) > 0 && !reflect.DeepEqual(got, c.diags) {
+				t.Errorf("got diagnostics %q, want %q", got, c.diags)
+			}
+
+			doc, err := genericJSON(out)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for expr, want := range c.want {
+				if got := selectJSON(t, doc, expr); got != want {
+					t.Errorf("%s: got %s, want %s", expr, got, want)
+				}
+			}
+		})
+	}
+}
+
+// selectJSON is the JSON array of the values expr selects in doc.
+func selectJSON(t *testing.T, doc interface{}, expr string) string {
+	t.Helper()
+	path, err := parseJSONPath(expr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	values := []interface{}{}
+	for _, n := range path.eval(&jsonNode{doc: &doc}) {
+		values = append(values, n.get())
+	}
+	b, err := json.Marshal(values)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
@@ -0,0 +1,153 @@
+package generator
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// patchOp is one operation of an RFC 6902 JSON Patch.
+type patchOp struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	From  string      `json:"from"`
+	Value interface{} `json:"value"`
+}
+
+func applyPatch(doc *interface{}, ops []patchOp) error {
+	for i, op := range ops {
+		if err := applyPatchOp(doc, op); err != nil {
+			return fmt.Errorf("patch operation %d (%s %s): %v", i, op.Op, op.Path, err)
+		}
+	}
+	return nil
+}
+
+func applyPatchOp(doc *interface{}, op patchOp) error {
+	switch op.Op {
+	case "add":
+		return patchAdd(doc, op.Path, op.Value)
+	case "remove":
+		n, err := resolvePointer(doc, op.Path)
+		if err != nil {
+			return err
+		}
+		n.remove()
+		return nil
+	case "replace":
+		n, err := resolvePointer(doc, op.Path)
+		if err != nil {
+			return err
+		}
+		n.set(op.Value)
+		return nil
+	case "move", "copy":
+		from, err := resolvePointer(doc, op.From)
+		if err != nil {
+			return err
+		}
+		v, err := genericJSON(from.get())
+		if err != nil {
+			return err
+		}
+		if op.Op == "move" {
+			if op.Path == op.From {
+				return nil
+			}
+			if strings.HasPrefix(op.Path, op.From+"/") {
+				return fmt.Errorf("cannot move %s into itself", op.From)
+			}
+			from.remove()
+		}
+		return patchAdd(doc, op.Path, v)
+	case "test":
+		n, err := resolvePointer(doc, op.Path)
+		if err != nil {
+			return err
+		}
+		if !jsonEqual(n.get(), op.Value) {
+			return fmt.Errorf("test failed")
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown operation")
+}
+
+func patchAdd(doc *interface{}, path string, v interface{}) error {
+	tokens, err := parsePointer(path)
+	if err != nil {
+		return err
+	}
+	if len(tokens) == 0 {
+		*doc = v
+		return nil
+	}
+
+	parent, err := resolveTokens(doc, tokens[:len(tokens)-1])
+	if err != nil {
+		return err
+	}
+	last := tokens[len(tokens)-1]
+	switch pv := parent.get().(type) {
+	case map[string]interface{}:
+		pv[last] = v
+	case []interface{}:
+		idx := len(pv)
+		if last != "-" {
+			if idx, err = strconv.Atoi(last); err != nil || idx < 0 || idx > len(pv) {
+				return fmt.Errorf("index %s out of range", last)
+			}
+		}
+		list := append([]interface{}{}, pv[:idx]...)
+		list = append(list, v)
+		parent.set(append(list, pv[idx:]...))
+	default:
+		return fmt.Errorf("parent of %s is not a container", path)
+	}
+	return nil
+}
+
+func resolvePointer(doc *interface{}, path string) (*jsonNode, error) {
+	tokens, err := parsePointer(path)
+	if err != nil {
+		return nil, err
+	}
+	return resolveTokens(doc, tokens)
+}
+
+func resolveTokens(doc *interface{}, tokens []string) (*jsonNode, error) {
+	n := &jsonNode{doc: doc}
+	for i, tok := range tokens {
+		switch cur := n.get().(type) {
+		case map[string]interface{}:
+			if _, ok := cur[tok]; !ok {
+				return nil, fmt.Errorf("/%s does not exist", strings.Join(tokens[:i+1], "/"))
+			}
+			n = &jsonNode{doc: doc, parent: n, key: tok}
+		case []interface{}:
+			idx, err := strconv.Atoi(tok)
+			if err != nil || idx < 0 || idx >= len(cur) {
+				return nil, fmt.Errorf("index %s out of range", tok)
+			}
+			n = &jsonNode{doc: doc, parent: n, idx: idx}
+		default:
+			return nil, fmt.Errorf("/%s does not exist", strings.Join(tokens[:i+1], "/"))
+		}
+	}
+	return n, nil
+}
+
+// parsePointer splits an RFC 6901 JSON Pointer into unescaped tokens.
+func parsePointer(path string) ([]string, error) {
+	if path == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(path, "/") {
+		return nil, fmt.Errorf("pointer %q does not start with /", path)
+	}
+	tokens := strings.Split(path[1:], "/")
+	for i, tok := range tokens {
+		tokens[i] = strings.Replace(strings.Replace(tok, "~1", "/", -1), "~0", "~", -1)
+	}
+	return tokens, nil
+}
@@ -0,0 +1,154 @@
+package generator
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestApplyPatch(t *testing.T) {
+	const doc = `{"a": {"b": 1, "c": [1, 2, 3]}, "x~y": {"p/q": true}}`
+	cases := []struct {
+		name  string
+		doc   string
+		patch string
+		want  string
+		err   string
+	}{
+		{
+			name:  "add",
+			patch: `[{"op": "add", "path": "/a/d", "value": {"e": null}}, {"op": "add", "path": "/a/c/1", "value": 9}, {"op": "add", "path": "/a/c/-", "value": 4}]`,
+			want:  `{"a": {"b": 1, "c": [1, 9, 2, 3, 4], "d": {"e": null}}, "x~y": {"p/q": true}}`,
+		},
+		{
+			name:  "add replaces members and the document",
+			patch: `[{"op": "add", "path": "/a/b", "value": 2}, {"op": "add", "path": "", "value": {"new": [0]}}, {"op": "add", "path": "/new/0", "value": -1}]`,
+			want:  `{"new": [-1, 0]}`,
+		},
+		{
+			name:  "escaped tokens",
+			patch: `[{"op": "replace", "path": "/x~0y/p~1q", "value": false}, {"op": "add", "path": "/~01", "value": "tilde one"}]`,
+			want:  `{"a": {"b": 1, "c": [1, 2, 3]}, "x~y": {"p/q": false}, "~1": "tilde one"}`,
+		},
+		{
+			name:  "remove",
+			patch: `[{"op": "remove", "path": "/a/c/0"}, {"op": "remove", "path": "/x~0y"}]`,
+			want:  `{"a": {"b": 1, "c": [2, 3]}}`,
+		},
+		{
+			name:  "replace",
+			patch: `[{"op": "replace", "path": "/a/c/2", "value": "three"}, {"op": "replace", "path": "/a/b", "value": [1]}]`,
+			want:  `{"a": {"b": [1], "c": [1, 2, "three"]}, "x~y": {"p/q": true}}`,
+		},
+		{
+			name:  "move",
+			patch: `[{"op": "move", "from": "/a/b", "path": "/b"}, {"op": "move", "from": "/a/c/0", "path": "/a/c/2"}, {"op": "move", "from": "/x~0y", "path": "/x~0y"}]`,
+			want:  `{"a": {"c": [2, 3, 1]}, "b": 1, "x~y": {"p/q": true}}`,
+		},
+		{
+			name:  "copy is deep",
+			patch: `[{"op": "copy", "from": "/a", "path": "/z"}, {"op": "add", "path": "/z/c/-", "value": 4}]`,
+			want:  `{"a": {"b": 1, "c": [1, 2, 3]}, "x~y": {"p/q": true}, "z": {"b": 1, "c": [1, 2, 3, 4]}}`,
+		},
+		{
+			name:  "test",
+			patch: `[{"op": "test", "path": "/a", "value": {"c": [1, 2, 3.0], "b": 1e0}}, {"op": "test", "path": "/x~0y/p~1q", "value": true}]`,
+			want:  doc,
+		},
+		{
+			name:  "failed test",
+			patch: `[{"op": "remove", "path": "/a/b"}, {"op": "test", "path": "/a/c", "value": [1, 2]}]`,
+			err:   "patch operation 1 (test /a/c): test failed",
+		},
+		{
+			name:  "test of a missing value",
+			patch: `[{"op": "test", "path": "/a/z", "value": null}]`,
+			err:   "patch operation 0 (test /a/z): /a/z does not exist",
+		},
+		{
+			name:  "move into itself",
+			patch: `[{"op": "move", "from": "/a", "path": "/a/c/0"}]`,
+			err:   "cannot move /a into itself",
+		},
+		{
+			name:  "move from a missing value",
+			patch: `[{"op": "move", "from": "/nope", "path": "/a"}]`,
+			err:   "/nope does not exist",
+		},
+		{
+			name:  "remove a missing member",
+			patch: `[{"op": "remove", "path": "/a/nope"}]`,
+			err:   "/a/nope does not exist",
+		},
+		{
+			name:  "replace a missing member",
+			patch: `[{"op": "replace", "path": "/nope", "value": 1}]`,
+			err:   "/nope does not exist",
+		},
+		{
+			name:  "index out of range",
+			patch: `[{"op": "add", "path": "/a/c/4", "value": 1}]`,
+			err:   "index 4 out of range",
+		},
+		{
+			name:  "bad index",
+			patch: `[{"op": "remove", "path": "/a/c/x"}]`,
+			err:   "index x out of range",
+		},
+		{
+			name:  "add below a missing parent",
+			patch: `[{"op": "add", "path": "/nope/x", "value": 1}]`,
+			err:   "/nope does not exist",
+		},
+		{
+			name:  "add below a value",
+			patch: `[{"op": "add", "path": "/a/b/x", "value": 1}]`,
+			err:   "parent of /a/b/x is not a container",
+		},
+		{
+			name:  "pointer without a slash",
+			patch: `[{"op": "remove", "path": "a"}]`,
+			err:   `pointer "a" does not start with /`,
+		},
+		{
+			name:  "unknown operation",
+			patch: `[{"op": "merge", "path": "/a"}]`,
+			err:   "patch operation 0 (merge /a): unknown operation",
+		},
+	}
+
+	decode := func(s string) interface{} {
+		dec := json.NewDecoder(strings.NewReader(s))
+		dec.UseNumber()
+		var v interface{}
+		if err := dec.Decode(&v); err != nil {
+			t.Fatalf("%s: %v", s, err)
+		}
+		return v
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			v := decode(doc)
+			var ops []patchOp
+			dec := json.NewDecoder(bytes.NewReader([]byte(c.patch)))
+			dec.UseNumber()
+			if err := dec.Decode(&ops); err != nil {
+				t.Fatal(err)
+			}
+
+			err := applyPatch(&v, ops)
+			switch {
+			case c.err != "":
+				if err == nil || !strings.Contains(err.Error(), c.err) {
+					t.Fatalf("got error %v, want %q", err, c.err)
+				}
+			case err != nil:
+				t.Fatal(err)
+			case !jsonEqual(v, decode(c.want)):
+				got, _ := json.Marshal(v)
+				t.Errorf("got %s, want %s", got, c.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,178 @@
+package generator
+
+import (
+	"strings"
+	"text/template"
+)
+
+// pathParam is a parameter of a path template: a whole :name segment, or
+// {name} anywhere in a segment, e.g. /files/{name}.json.
+type pathParam struct {
+	Name string
+	// Placeholder is the text standing for the parameter, :name or {name}.
+	Placeholder string
+}
+
+// segmentParams are the parameters of a segment of a path template.
+func segmentParams(seg string) []pathParam {
+	if strings.HasPrefix(seg, ":") {
+		return []pathParam{{Name: seg[1:], Placeholder: seg}}
+	}
+	params := []pathParam{}
+	for _, m := range rxPathParam.FindAllString(seg, -1) {
+		params = append(params, pathParam{Name: m[1 : len(m)-1], Placeholder: m})
+	}
+	return params
+}
+
+// PathParams are the parameter names of path.
+func PathParams(path string) []string {
+	names := []string{}
+	for _, seg := range strings.Split(path, "/") {
+		for _, p := range segmentParams(seg) {
+			names = append(names, p.Name)
+		}
+	}
+	return names
+}
+
+// NormalizePath replaces every path parameter with an anonymous
+// placeholder, so that paths differing only by parameter names compare
+// equal.
+func NormalizePath(path string) string {
+	segs := strings.Split(path, "/")
+	for i, seg := range segs {
+		for _, p := range segmentParams(seg) {
+			seg = strings.Replace(seg, p.Placeholder, "{}", 1)
+		}
+		segs[i] = seg
+	}
+	return strings.Join(segs, "/")
+}
+
+func splitPath(path string) []string {
+	return strings.Split(strings.Trim(path, "/"), "/")
+}
+
+// matchPath reports whether path fits the route template and returns the
+// values of its parameters.
+func matchPath(template, path []string) (map[string]string, bool) {
+	if len(template) != len(path) {
+		return nil, false
+	}
+	params := map[string]string{}
+	for i, seg := range template {
+		if !matchSegment(seg, path[i], params) {
+			return nil, false
+		}
+	}
+	return params, true
+}
+
+// matchSegment matches a segment of a path against one of a template. A
+// :name segment takes the whole segment; {name} parameters between
+// literals take the text up to the next literal, and none may be empty.
+func matchSegment(template, seg string, params map[string]string) bool {
+	if strings.HasPrefix(template, ":") {
+		if seg == "" {
+			return false
+		}
+		params[template[1:]] = seg
+		return true
+	}
+	locs := rxPathParam.FindAllStringIndex(template, -1)
+	if len(locs) == 0 {
+		return template == seg
+	}
+	rest, prev := seg, 0
+	for i, loc := range locs {
+		lit := template[prev:loc[0]]
+		if !strings.HasPrefix(rest, lit) {
+			return false
+		}
+		rest = rest[len(lit):]
+
+		end := 0
+		if i == len(locs)-1 {
+			suffix := template[loc[1]:]
+			if !strings.HasSuffix(rest, suffix) {
+				return false
+			}
+			end = len(rest) - len(suffix)
+		} else {
+			end = strings.Index(rest, template[loc[1]:locs[i+1][0]])
+		}
+		if end <= 0 {
+			return false
+		}
+		params[template[loc[0]+1:loc[1]-1]] = rest[:end]
+		rest, prev = rest[end:], loc[1]
+	}
+	return true
+}
+
+// goPathHelpers is splitPath and matchPath written out for generated code,
+// with names starting with prefix so that the code of several generators
+// can share a package.
+func goPathHelpers(prefix string) string {
+	var b strings.Builder
+	goPathTemplate.Execute(&b, prefix)
+	return b.String()
+}
+
+var goPathTemplate = template.Must(template.New("").Parse(`
+func {{.}}SplitPath(path string) []string {
+	return strings.Split(strings.Trim(path, "/"), "/")
+}
+
+// {{.}}MatchPath reports whether path fits the route template and returns
+// the values of its :name segments and {name} parameters.
+func {{.}}MatchPath(template, path []string) (map[string]string, bool) {
+	if len(template) != len(path) {
+		return nil, false
+	}
+	params := map[string]string{}
+	for i, seg := range template {
+		if !{{.}}MatchSegment(seg, path[i], params) {
+			return nil, false
+		}
+	}
+	return params, true
+}
+
+// {{.}}MatchSegment matches a segment of a path against one of a template,
+// each {name} parameter taking the text up to the next literal.
+func {{.}}MatchSegment(template, seg string, params map[string]string) bool {
+	if strings.HasPrefix(template, ":") {
+		params[template[1:]] = seg
+		return seg != ""
+	}
+	for {
+		start := strings.Index(template, "{")
+		end := strings.Index(template[start+1:], "}") + start + 1
+		if start < 0 || end <= start {
+			return template == seg
+		}
+		if !strings.HasPrefix(seg, template[:start]) {
+			return false
+		}
+		name, lit := template[start+1:end], template[end+1:]
+		seg, template = seg[start:], lit
+		if next := strings.Index(lit, "{"); next >= 0 {
+			lit = lit[:next]
+		}
+		n := strings.Index(seg, lit)
+		if template == lit {
+			n = len(seg) - len(lit)
+			if !strings.HasSuffix(seg, lit) {
+				n = -1
+			}
+		}
+		if n <= 0 {
+			return false
+		}
+		params[name] = seg[:n]
+		seg = seg[n:]
+	}
+}
+`))
@@ -0,0 +1,92 @@
+package generator
+
+import (
+	"reflect"
+	"testing"
+)
+
+// matchSegmentCases are shared with the test of the helpers emitted for
+// generated code, which must match the same way. A nil params means no
+// match.
+var matchSegmentCases = []struct {
+	template, seg string
+	params        map[string]string
+}{
+	{"users", "users", map[string]string{}},
+	{"users", "user", nil},
+	{"users", "", nil},
+	{"", "", map[string]string{}},
+	{":id", "42", map[string]string{"id": "42"}},
+	{":id", "", nil},
+	{"{id}", "42", map[string]string{"id": "42"}},
+	{"{id}", "", nil},
+	{"{name}.json", "a.json", map[string]string{"name": "a"}},
+	{"{name}.json", "a.b.json", map[string]string{"name": "a.b"}},
+	{"{name}.json", ".json", nil},
+	{"{name}.json", "a.yaml", nil},
+	{"v{major}", "v2", map[string]string{"major": "2"}},
+	{"v{major}", "v", nil},
+	{"v{major}", "x2", nil},
+	{"v{major}.{minor}", "v1.2", map[string]string{"major": "1", "minor": "2"}},
+	{"v{major}.{minor}", "v1.2.3", map[string]string{"major": "1", "minor": "2.3"}},
+	{"v{major}.{minor}", "v1.", nil},
+	{"v{major}.{minor}", "v.2", nil},
+	{"{from}-{to}.csv", "2020-2021.csv", map[string]string{"from": "2020", "to": "2021"}},
+	{"{from}-{to}.csv", "2020-2021.txt", nil},
+	{"{a}{b}", "ab", nil},
+	{"{open", "{open", map[string]string{}},
+	{"{open", "x", nil},
+}
+
+func TestMatchSegment(t *testing.T) {
+	for _, c := range matchSegmentCases {
+		params := map[string]string{}
+		ok := matchSegment(c.template, c.seg, params)
+		if ok != (c.params != nil) || ok && !reflect.DeepEqual(params, c.params) {
+			t.Errorf("matchSegment(%q, %q) = %v, %v; want %v", c.template, c.seg, params, ok, c.params)
+		}
+	}
+}
+
+func TestMatchPath(t *testing.T) {
+	cases := []struct {
+		template, path string
+		params         map[string]string
+	}{
+		{"/users/{id}", "/users/42", map[string]string{"id": "42"}},
+		{"/users/{id}", "/users/42/", map[string]string{"id": "42"}},
+		{"/users/{id}", "/users", nil},
+		{"/users/{id}", "/users/42/posts", nil},
+		{"/users/:id/files/{name}.json", "/users/7/files/a.json", map[string]string{"id": "7", "name": "a"}},
+		{"/", "/", map[string]string{}},
+		{"/", "/users", nil},
+	}
+	for _, c := range cases {
+		params, ok := matchPath(splitPath(c.template), splitPath(c.path))
+		if ok != (c.params != nil) || ok && !reflect.DeepEqual(params, c.params) {
+			t.Errorf("matchPath(%q, %q) = %v, %v; want %v", c.template, c.path, params, ok, c.params)
+		}
+	}
+}
+
+func TestPathParams(t *testing.T) {
+	cases := []struct {
+		path       string
+		params     []string
+		normalized string
+	}{
+		{"/users", []string{}, "/users"},
+		{"/users/{id}", []string{"id"}, "/users/{}"},
+		{"/users/:id", []string{"id"}, "/users/{}"},
+		{"/users/{uid}/files/{name}.{ext}", []string{"uid", "name", "ext"}, "/users/{}/files/{}.{}"},
+		{"/v{major}/a:b", []string{"major"}, "/v{}/a:b"},
+	}
+	for _, c := range cases {
+		if got := PathParams(c.path); !reflect.DeepEqual(got, c.params) {
+			t.Errorf("PathParams(%q) = %q, want %q", c.path, got, c.params)
+		}
+		if got := NormalizePath(c.path); got != c.normalized {
+			t.Errorf("NormalizePath(%q) = %q, want %q", c.path, got, c.normalized)
+		}
+	}
+}
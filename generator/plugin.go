@@ -0,0 +1,104 @@
+package generator
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"go/token"
+	"os/exec"
+	"strings"
+
+	"github.com/go-openapi/spec"
+)
+
+// PluginVersion is sent to plugins so they can refuse requests they do not
+// understand.
+const PluginVersion = "1"
+
+// pluginRequest is what a plugin reads from stdin. It answers on stdout
+// with either a complete spec object or a JSON Patch array against spec;
+// empty output leaves the spec unchanged. A non-zero exit status fails the
+// plugin and its stderr becomes the diagnostic.
+type pluginRequest struct {
+	Version string        `json:"version"`
+	Model   *Model        `json:"model"`
+	Spec    *spec.Swagger `json:"spec"`
+}
+
+// runPlugins runs each plugin command on the spec in turn. A failing plugin
+// is reported and skipped, the next one sees the spec from before it.
+func runPlugins(ctx context.Context, dir string, sw *spec.Swagger, s *Scanner, plugins []string, diags *Diagnostics) *spec.Swagger {
+	if len(plugins) == 0 {
+		return sw
+	}
+
+	m := s.Model()
+	for _, plugin := range plugins {
+		out, err := runPlugin(ctx, dir, plugin, sw, m)
+		if err != nil {
+			diags.Errorf(token.Position{}, CodePlugin, "plugin %s: %v", plugin, err)
+			continue
+		}
+		sw = out
+	}
+	return sw
+}
+
+func runPlugin(ctx context.Context, dir, plugin string, sw *spec.Swagger, m *Model) (*spec.Swagger, error) {
+	args := strings.Fields(plugin)
+	if len(args) == 0 {
+		return nil, fmt.Errorf("empty command")
+	}
+
+	in, err := json.Marshal(pluginRequest{Version: PluginVersion, Model: m, Spec: sw})
+	if err != nil {
+		return nil, err
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Dir = dir
+	cmd.Stdin = bytes.NewReader(in)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%v: %s", err, msg)
+		}
+		return nil, err
+	}
+
+	out := bytes.TrimSpace(stdout.Bytes())
+	if len(out) == 0 {
+		return sw, nil
+	}
+
+	next := new(spec.Swagger)
+	switch out[0] {
+	case '{':
+		if err := json.Unmarshal(out, next); err != nil {
+			return nil, fmt.Errorf("invalid spec: %v", err)
+		}
+	case '[':
+		var ops []patchOp
+		dec := json.NewDecoder(bytes.NewReader(out))
+		dec.UseNumber()
+		if err := dec.Decode(&ops); err != nil {
+			return nil, fmt.Errorf("invalid JSON Patch: %v", err)
+		}
+		doc, err := genericJSON(sw)
+		if err != nil {
+			return nil, err
+		}
+		if err := applyPatch(&doc, ops); err != nil {
+			return nil, err
+		}
+		if err := fromGeneric(doc, next); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("output is neither a spec object nor a JSON Patch array")
+	}
+	return next, nil
+}
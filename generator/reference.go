@@ -0,0 +1,376 @@
+package generator
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/go-openapi/spec"
+)
+
+// Markdown renders the spec as a reference document: one section per tag
+// with its operations, parameters, request fields, responses and examples,
+// followed by the models.
+func Markdown(sw *spec.Swagger) ([]byte, error) {
+	r := reference{sw: sw}
+
+	title := "API reference"
+	if sw.Info != nil && sw.Info.Title != "" {
+		title = sw.Info.Title
+	}
+	r.printf("# %s\n\n", title)
+	if sw.Info != nil {
+		if sw.Info.Version != "" {
+			r.printf("Version %s\n\n", sw.Info.Version)
+		}
+		if sw.Info.Description != "" {
+			r.printf("%s\n\n", strings.TrimSpace(sw.Info.Description))
+		}
+	}
+	if sw.Host != "" || sw.BasePath != "" {
+		r.printf("Base URL: `%s%s`\n\n", sw.Host, sw.BasePath)
+	}
+
+	tags, ops := r.operationsByTag()
+	for _, tag := range tags {
+		r.printf("## %s\n\n", tag)
+		for _, op := range ops[tag] {
+			r.operation(op)
+		}
+	}
+
+	if len(sw.Definitions) > 0 {
+		r.printf("## Models\n\n")
+		names := []string{}
+		for name := range sw.Definitions {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			schema := sw.Definitions[name]
+			r.printf("### %s\n\n", name)
+			if schema.Description != "" {
+				r.printf("%s\n\n", strings.TrimSpace(schema.Description))
+			}
+			r.fields(&schema)
+		}
+	}
+
+	return r.buf.Bytes(), nil
+}
+
+// HTML is a standalone documentation page with the spec inlined, for
+// publishing where pages cannot fetch anything.
+func HTML(sw *spec.Swagger) ([]byte, error) {
+	b, err := Marshal(sw, false, false)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(DocPage(DocPageConfig{SpecData: b})), nil
+}
+
+type reference struct {
+	sw  *spec.Swagger
+	buf bytes.Buffer
+}
+
+type refOperation struct {
+	method, path string
+	op           *spec.Operation
+}
+
+func (self *reference) printf(format string, args ...interface{}) {
+	fmt.Fprintf(&self.buf, format, args...)
+}
+
+// operationsByTag groups operations under their most specific, i.e. last,
+// tag, the same way the documentation page does.
+func (self *reference) operationsByTag() ([]string, map[string][]refOperation) {
+	ops := map[string][]refOperation{}
+	if self.sw.Paths == nil {
+		return nil, ops
+	}
+
+	paths := []string{}
+	for p := range self.sw.Paths.Paths {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	for _, p := range paths {
+		item := self.sw.Paths.Paths[p]
+		for _, m := range []struct {
+			name string
+			op   *spec.Operation
+		}{{"GET", item.Get}, {"POST", item.Post}, {"PUT", item.Put}, {"PATCH", item.Patch}, {"DELETE", item.Delete}, {"HEAD", item.Head}, {"OPTIONS", item.Options}} {
+			if m.op == nil {
+				continue
+			}
+			tag := "default"
+			if len(m.op.Tags) > 0 {
+				tag = m.op.Tags[len(m.op.Tags)-1]
+			}
+			ops[tag] = append(ops[tag], refOperation{method: m.name, path: p, op: m.op})
+		}
+	}
+
+	tags := []string{}
+	for tag := range ops {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	return tags, ops
+}
+
+func (self *reference) operation(o refOperation) {
+	op := o.op
+	self.printf("### `%s %s`\n\n", o.method, o.path)
+	if op.Summary != "" {
+		self.printf("**%s**\n\n", op.Summary)
+	}
+	if op.Description != "" {
+		self.printf("%s\n\n", strings.TrimSpace(op.Description))
+	}
+	if op.ID != "" {
+		self.printf("Operation ID: `%s`\n\n", op.ID)
+	}
+
+	var body *spec.Parameter
+	params := []spec.Parameter{}
+	for _, p := range op.Parameters {
+		if p.In == "body" {
+			p := p
+			body = &p
+			continue
+		}
+		params = append(params, p)
+	}
+
+	if len(params) > 0 {
+		self.printf("| Parameter | In | Type | Required | Description |\n|---|---|---|---|---|\n")
+		for _, p := range params {
+			self.printf("| `%s` | %s | %s | %s | %s |\n", p.Name, p.In, paramType(&p), yesNo(p.Required), cell(p.Description))
+		}
+		self.printf("\n")
+	}
+
+	if body != nil {
+		self.printf("#### Request body\n\n")
+		if desc := strings.TrimSpace(body.Description); desc != "" {
+			self.printf("%s\n\n", desc)
+		}
+		self.fields(body.Schema)
+		self.example(body.Schema)
+	}
+
+	if op.Responses != nil && len(op.Responses.StatusCodeResponses) > 0 || op.Responses != nil && op.Responses.Default != nil {
+		self.printf("#### Responses\n\n| Code | Description | Schema |\n|---|---|---|\n")
+		codes := []int{}
+		for code := range op.Responses.StatusCodeResponses {
+			codes = append(codes, code)
+		}
+		sort.Ints(codes)
+		for _, code := range codes {
+			resp := op.Responses.StatusCodeResponses[code]
+			self.printf("| %d | %s | %s |\n", code, cell(resp.Description), self.typeName(resp.Schema))
+		}
+		if d := op.Responses.Default; d != nil {
+			self.printf("| default | %s | %s |\n", cell(d.Description), self.typeName(d.Schema))
+		}
+		self.printf("\n")
+		for _, code := range codes {
+			resp := op.Responses.StatusCodeResponses[code]
+			if resp.Schema != nil {
+				self.printf("Example %d response:\n\n", code)
+				self.example(resp.Schema)
+			}
+		}
+	}
+}
+
+// fields prints the properties of an object schema as a table.
+func (self *reference) fields(schema *spec.Schema) {
+	schema = self.resolve(schema, map[string]bool{})
+	if schema == nil || len(schema.Properties) == 0 {
+		return
+	}
+	required := map[string]bool{}
+	for _, name := range schema.Required {
+		required[name] = true
+	}
+	self.printf("| Field | Type | Required | Description |\n|---|---|---|---|\n")
+	for _, name := range propertyNames(schema) {
+		prop := schema.Properties[name]
+		self.printf("| `%s` | %s | %s | %s |\n", name, self.typeName(&prop), yesNo(required[name]), cell(prop.Description))
+	}
+	self.printf("\n")
+}
+
+func (self *reference) example(schema *spec.Schema) {
+	b, err := json.MarshalIndent(self.exampleValue(schema, map[string]bool{}), "", "  ")
+	if err != nil {
+		return
+	}
+	self.printf("```json\n%s\n```\n\n", b)
+}
+
+func (self *reference) exampleValue(schema *spec.Schema, seen map[string]bool) interface{} {
+	if schema == nil {
+		return nil
+	}
+	if schema.Ref.String() != "" {
+		name := refName(schema)
+		if seen[name] {
+			return nil
+		}
+		seen[name] = true
+		defer delete(seen, name)
+	}
+	schema = self.resolve(schema, map[string]bool{})
+	if schema == nil {
+		return nil
+	}
+	if schema.Example != nil {
+		return schema.Example
+	}
+	if len(schema.Enum) > 0 {
+		return schema.Enum[0]
+	}
+
+	switch {
+	case schema.Type.Contains("object") || len(schema.Properties) > 0:
+		out := orderedMap{}
+		for _, name := range propertyNames(schema) {
+			prop := schema.Properties[name]
+			out = append(out, orderedEntry{Key: name, Value: self.exampleValue(&prop, seen)})
+		}
+		return out
+	case schema.Type.Contains("array"):
+		if schema.Items != nil && schema.Items.Schema != nil {
+			return []interface{}{self.exampleValue(schema.Items.Schema, seen)}
+		}
+		return []interface{}{}
+	case schema.Type.Contains("integer"), schema.Type.Contains("number"):
+		return 0
+	case schema.Type.Contains("boolean"):
+		return false
+	case schema.Type.Contains("string"):
+		if schema.Format == "date-time" {
+			return "1970-01-01T00:00:00Z"
+		}
+		return "string"
+	}
+	return nil
+}
+
+func (self *reference) resolve(schema *spec.Schema, seen map[string]bool) *spec.Schema {
+	for schema != nil && schema.Ref.String() != "" {
+		name := refName(schema)
+		def, ok := self.sw.Definitions[name]
+		if !ok || seen[name] {
+			return nil
+		}
+		seen[name] = true
+		schema = &def
+	}
+	return schema
+}
+
+// typeName describes a schema in a table cell, linking to models.
+func (self *reference) typeName(schema *spec.Schema) string {
+	if schema == nil {
+		return ""
+	}
+	if schema.Ref.String() != "" {
+		name := refName(schema)
+		return fmt.Sprintf("[%s](#%s)", name, anchor(name))
+	}
+	if schema.Type.Contains("array") && schema.Items != nil && schema.Items.Schema != nil {
+		return "array of " + self.typeName(schema.Items.Schema)
+	}
+	t := strings.Join(schema.Type, ", ")
+	if schema.Format != "" {
+		t += " (" + schema.Format + ")"
+	}
+	if len(schema.Enum) > 0 {
+		values := []string{}
+		for _, v := range schema.Enum {
+			values = append(values, fmt.Sprintf("`%v`", v))
+		}
+		t += ": " + strings.Join(values, ", ")
+	}
+	return t
+}
+
+func paramType(p *spec.Parameter) string {
+	if p.Type == "array" && p.Items != nil {
+		return "array of " + p.Items.Type
+	}
+	if p.Format != "" {
+		return p.Type + " (" + p.Format + ")"
+	}
+	return p.Type
+}
+
+// propertyNames orders properties by x-order, then by name.
+func propertyNames(schema *spec.Schema) []string {
+	names := []string{}
+	for name := range schema.Properties {
+		names = append(names, name)
+	}
+	order := func(name string) (int64, bool) {
+		prop := schema.Properties[name]
+		v, err := genericJSON(prop)
+		if err != nil {
+			return 0, false
+		}
+		return propertyOrder(v)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		oi, iok := order(names[i])
+		oj, jok := order(names[j])
+		if iok != jok {
+			return iok
+		}
+		if iok && oi != oj {
+			return oi < oj
+		}
+		return names[i] < names[j]
+	})
+	return names
+}
+
+func refName(schema *spec.Schema) string {
+	return strings.TrimPrefix(schema.Ref.String(), "#/definitions/")
+}
+
+// anchor is the heading id most Markdown renderers generate.
+func anchor(heading string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '-', r == '_':
+			return r
+		case r >= 'A' && r <= 'Z':
+			return r + 'a' - 'A'
+		case r == ' ':
+			return '-'
+		}
+		return -1
+	}, heading)
+}
+
+// cell makes text safe for a table cell, keeping its line breaks.
+func cell(s string) string {
+	s = strings.TrimSpace(s)
+	s = strings.Replace(s, "|", "\\|", -1)
+	return strings.Replace(s, "\n", "<br>", -1)
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
@@ -1,4 +1,4 @@
-package main
+package generator
 
 import "regexp"
 
@@ -23,7 +23,12 @@ var (
 		"swag:ans\\p{Zs}*" +
 			rxID + "\\p{Zs}*" +
 			rxStatusCode + "\\p{Zs}*$")
+	rxGroup = regexp.MustCompile(
+		"swag:group\\p{Zs}*" +
+			rxPath + "(?:\\p{Zs}+" +
+			rxTags + ")?\\p{Zs}*$")
 
+	rxPathParam     = regexp.MustCompile(`\{[^{}]*\}`)
 	rxSpace         = regexp.MustCompile(`\p{Zs}+`)
 	rxStripComments = regexp.MustCompile(`^[^\p{L}\p{N}\p{Pd}\p{Pc}\+]*`)
 )
@@ -0,0 +1,599 @@
+package generator
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"golang.org/x/tools/go/packages"
+)
+
+type node uint32
+
+const (
+	metaNode node = 1 << iota
+	routeNode
+	reqNode
+	ansNode
+	groupNode
+)
+
+type Scanner struct {
+	metas  []*Meta
+	routes map[string]*Route
+	reqs   map[string]*Decl
+	anses  map[string]*Decl
+	groups map[string]*Group
+	files  []string
+	diags  *Diagnostics
+}
+
+// pkgScan is what a single package contributes to a scan. Once built it
+// holds no go/types state, so it can be cached between runs.
+type pkgScan struct {
+	Path   string
+	Metas  []*Meta
+	Group  *Group
+	Routes []*Route
+	Decls  []pkgDecl
+	Diags  []*Diagnostic
+}
+
+type pkgDecl struct {
+	*Decl
+	Fragment *fragment
+}
+
+func newScanner(diags *Diagnostics) *Scanner {
+	return &Scanner{
+		diags:  diags,
+		metas:  []*Meta{},
+		routes: map[string]*Route{},
+		reqs:   map[string]*Decl{},
+		anses:  map[string]*Decl{},
+		groups: map[string]*Group{},
+	}
+}
+
+// merged combines package scans in package path order, whatever order they
+// were produced in.
+func merged(scans []*pkgScan, diags *Diagnostics) *Scanner {
+	scans = append([]*pkgScan{}, scans...)
+	sort.Slice(scans, func(i, j int) bool { return scans[i].Path < scans[j].Path })
+
+	s := newScanner(diags)
+	for _, ps := range scans {
+		s.add(ps)
+	}
+	return s
+}
+
+func (self *Scanner) add(ps *pkgScan) {
+	self.diags.Append(ps.Diags...)
+	self.metas = append(self.metas, ps.Metas...)
+	if ps.Group != nil {
+		self.groups[ps.Path] = ps.Group
+	}
+
+	for _, route := range ps.Routes {
+		if first, known := self.routes[route.ID]; known {
+			self.diags.Errorf(route.Pos, CodeDuplicateRoute, "duplicate route id %s, first declared at %s", route.ID, first.Pos).Relate(first.Pos)
+			continue
+		}
+		self.routes[route.ID] = route
+	}
+
+	for _, d := range ps.Decls {
+		d.frag = d.Fragment
+		if d.HasReq {
+			self.reqs[d.ID] = d.Decl
+		}
+		if d.HasAns {
+			self.anses[d.Name] = d.Decl
+		}
+	}
+}
+
+// scanPackages scans the files of pkgs on up to jobs goroutines and joins
+// the results in package and file order, so they do not depend on timing.
+func scanPackages(pkgs []*packages.Package, mappers []TypeMapper, jobs int) ([]*pkgScan, error) {
+	if jobs < 1 {
+		jobs = runtime.GOMAXPROCS(0)
+	}
+
+	type job struct{ pkg, file int }
+	results := make([][]*pkgScan, len(pkgs))
+	errs := make([][]error, len(pkgs))
+	queue := make(chan job)
+	for i, pkg := range pkgs {
+		results[i] = make([]*pkgScan, len(pkg.Syntax))
+		errs[i] = make([]error, len(pkg.Syntax))
+	}
+
+	var wg sync.WaitGroup
+	for w := 0; w < jobs; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range queue {
+				pkg := pkgs[j.pkg]
+				results[j.pkg][j.file], errs[j.pkg][j.file] = scanFile(pkg, pkg.Syntax[j.file], mappers)
+			}
+		}()
+	}
+	for i, pkg := range pkgs {
+		for f := range pkg.Syntax {
+			queue <- job{i, f}
+		}
+	}
+	close(queue)
+	wg.Wait()
+
+	scans := []*pkgScan{}
+	for i, pkg := range pkgs {
+		ps := pkgScan{Path: pkg.PkgPath}
+		diags := new(Diagnostics)
+		for _, e := range pkg.Errors {
+			diags.Errorf(parsePosition(e.Pos), CodePackage, "%s", e.Msg)
+		}
+
+		for f, fs := range results[i] {
+			if errs[i][f] != nil {
+				return nil, errs[i][f]
+			}
+			ps.Metas = append(ps.Metas, fs.Metas...)
+			switch {
+			case fs.Group == nil:
+			case ps.Group == nil:
+				ps.Group = fs.Group
+			case !ps.Group.same(fs.Group):
+				diags.Errorf(fs.Group.Pos, CodeGroupConflict, "package %s: swag:group conflicts with the one at %s, which is kept", pkg.PkgPath, ps.Group.Pos).Relate(ps.Group.Pos)
+			}
+			ps.Routes = append(ps.Routes, fs.Routes...)
+			ps.Decls = append(ps.Decls, fs.Decls...)
+			diags.Append(fs.Diags...)
+		}
+		ps.Diags = diags.List()
+		scans = append(scans, &ps)
+	}
+	return scans, nil
+}
+
+// scanFile collects the annotations of one file of pkg. It only reads the
+// package, so files can be scanned concurrently.
+func scanFile(pkg *packages.Package, file *ast.File, mappers []TypeMapper) (*pkgScan, error) {
+	ps := pkgScan{Path: pkg.PkgPath}
+	diags := new(Diagnostics)
+
+	n, err := detectNodes(pkg.Fset, file, diags)
+	if err != nil {
+		return nil, err
+	}
+
+	if n&metaNode != 0 {
+		ps.Metas = append(ps.Metas, &Meta{Comments: file.Doc})
+	}
+
+	if n&groupNode != 0 && file.Doc != nil {
+		group := parseGroup(pkg.Fset, file.Doc.List)
+		if group.Prefix != "" || len(group.Tags) > 0 {
+			ps.Group = group
+		}
+	}
+
+	if n&routeNode != 0 {
+		for _, cmts := range file.Comments {
+			route := parseRoute(pkg.Fset, cmts.List)
+			if route.Method != "" {
+				route.Pkg = pkg.PkgPath
+				ps.Routes = append(ps.Routes, route)
+			}
+		}
+	}
+
+	if n&reqNode != 0 || n&ansNode != 0 {
+		for _, dt := range file.Decls {
+			switch fd := dt.(type) {
+			case *ast.BadDecl:
+				continue
+			case *ast.FuncDecl:
+				continue
+			case *ast.GenDecl:
+				decls := parseDecl(pkg, file, n, fd)
+				for _, decl := range decls {
+					req, ans := decl.HasReqAnno(), decl.HasAnsAnno()
+					if req || ans {
+						ps.Decls = append(ps.Decls, pkgDecl{Decl: decl, Fragment: newFragment(decl, mappers)})
+					}
+				}
+			}
+		}
+	}
+
+	ps.Diags = diags.List()
+	return &ps, nil
+}
+
+// Routes returns the routes in package, file and position order.
+func (self *Scanner) Routes() []*Route {
+	routes := []*Route{}
+	for _, r := range self.routes {
+		routes = append(routes, r)
+	}
+	sort.Slice(routes, func(i, j int) bool {
+		return lessSource(routes[i].Pkg, routes[i].Pos, routes[j].Pkg, routes[j].Pos)
+	})
+	return routes
+}
+
+// Route looks a route up by its id.
+func (self *Scanner) Route(id string) (*Route, bool) {
+	r, ok := self.routes[id]
+	return r, ok
+}
+
+// Requests returns the swag:req decls in package, file and position order.
+func (self *Scanner) Requests() []*Decl {
+	return sortedDecls(self.reqs)
+}
+
+// Responses returns the swag:ans decls in package, file and position order.
+func (self *Scanner) Responses() []*Decl {
+	return sortedDecls(self.anses)
+}
+
+// Files returns the Go files of every package passing the include and
+// exclude patterns, annotated or not, e.g. to watch them for changes.
+func (self *Scanner) Files() []string {
+	return self.files
+}
+
+// Metas returns the swag:meta comments in scan order.
+func (self *Scanner) Metas() []*Meta {
+	return self.metas
+}
+
+func sortedDecls(decls map[string]*Decl) []*Decl {
+	list := []*Decl{}
+	for _, d := range decls {
+		list = append(list, d)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Pos() == list[j].Pos() {
+			return list[i].Name < list[j].Name
+		}
+		return lessSource(list[i].PkgPath, list[i].Pos(), list[j].PkgPath, list[j].Pos())
+	})
+	return list
+}
+
+func lessSource(pkgA string, posA token.Position, pkgB string, posB token.Position) bool {
+	if pkgA != pkgB {
+		return pkgA < pkgB
+	}
+	if posA.Filename != posB.Filename {
+		return posA.Filename < posB.Filename
+	}
+	return posA.Offset < posB.Offset
+}
+
+func detectNodes(fset *token.FileSet, file *ast.File, diags *Diagnostics) (node, error) {
+	declDocs := map[*ast.CommentGroup]bool{}
+	for _, dt := range file.Decls {
+		if gd, ok := dt.(*ast.GenDecl); ok && gd.Tok == token.TYPE && gd.Doc != nil {
+			declDocs[gd.Doc] = true
+		}
+	}
+
+	var n node
+	for _, comments := range file.Comments {
+		for _, cline := range comments.List {
+			if cline == nil {
+				continue
+			}
+
+			for _, line := range strings.Split(cline.Text, "\n") {
+				matches := rxSwag.FindStringSubmatch(line)
+				if len(matches) < 2 {
+					continue
+				}
+
+				pos := fset.Position(cline.Pos())
+				switch matches[1] {
+				case "meta":
+					n |= metaNode
+				case "route":
+					n |= routeNode
+					if !rxRoute.MatchString(line) {
+						diags.Errorf(pos, CodeMalformedAnno, "malformed swag:route, want: swag:route <id> <method> <path> [tags...]")
+					}
+				case "req":
+					n |= reqNode
+					if !rxReq.MatchString(line) {
+						diags.Errorf(pos, CodeMalformedAnno, "malformed swag:req, want: swag:req <route id>")
+					} else if !declDocs[comments] {
+						diags.Warnf(pos, CodeDetachedAnno, "swag:req is not part of a type declaration comment")
+					}
+				case "ans":
+					n |= ansNode
+					if m := rxAns.FindStringSubmatch(line); m == nil {
+						diags.Errorf(pos, CodeMalformedAnno, "malformed swag:ans, want: swag:ans <route id> <status code>")
+					} else if !validStatusCode(m[2]) {
+						diags.Errorf(pos, CodeBadStatusCode, "swag:ans %s: invalid status code %s", m[1], m[2])
+					} else if !declDocs[comments] {
+						diags.Warnf(pos, CodeDetachedAnno, "swag:ans is not part of a type declaration comment")
+					}
+				case "group":
+					n |= groupNode
+					if !rxGroup.MatchString(line) {
+						diags.Errorf(pos, CodeMalformedAnno, "malformed swag:group, want: swag:group <path prefix> [tags...]")
+					} else if comments != file.Doc {
+						diags.Warnf(pos, CodeDetachedAnno, "swag:group is only honoured in the package comment")
+					}
+				default:
+					diags.Warnf(pos, CodeUnknownAnno, "unknown annotation swag:%s", matches[1])
+				}
+			}
+		}
+	}
+	return n, nil
+}
+
+func validStatusCode(code string) bool {
+	c, err := strconv.Atoi(code)
+	return err == nil && c >= 100 && c <= 599
+}
+
+type Meta struct {
+	Comments *ast.CommentGroup
+}
+
+type Group struct {
+	Prefix string
+	Tags   []string
+	Pos    token.Position
+}
+
+// same reports whether both groups give a route the same prefix and tags.
+func (self *Group) same(other *Group) bool {
+	return self.Prefix == other.Prefix && strings.Join(self.Tags, " ") == strings.Join(other.Tags, " ")
+}
+
+func parseGroup(fset *token.FileSet, lines []*ast.Comment) *Group {
+	group := Group{}
+	for _, cmt := range lines {
+		for _, line := range strings.Split(cmt.Text, "\n") {
+			matches := rxGroup.FindStringSubmatch(line)
+			if len(matches) > 2 {
+				group.Pos = fset.Position(cmt.Pos())
+				group.Prefix = strings.TrimSuffix(matches[1], "/")
+				if len(matches[2]) != 0 {
+					group.Tags = rxSpace.Split(matches[2], -1)
+				}
+			}
+		}
+	}
+	return &group
+}
+
+// RouteGroup composes the groups of pkgPath and of every scanned package
+// above it, outermost first.
+func (self *Scanner) RouteGroup(pkgPath string) (prefix string, tags []string) {
+	parents := []string{}
+	for p := range self.groups {
+		if p == pkgPath || strings.HasPrefix(pkgPath, p+"/") {
+			parents = append(parents, p)
+		}
+	}
+	sort.Slice(parents, func(i, j int) bool { return len(parents[i]) < len(parents[j]) })
+
+	for _, p := range parents {
+		group := self.groups[p]
+		prefix += group.Prefix
+		tags = append(tags, group.Tags...)
+	}
+	return prefix, tags
+}
+
+// RoutePath is the path of r with the prefixes of its package groups applied.
+func (self *Scanner) RoutePath(r *Route) string {
+	prefix, _ := self.RouteGroup(r.Pkg)
+	if prefix == "" {
+		return r.Path
+	}
+	if r.Path == "/" {
+		return prefix
+	}
+	return prefix + "/" + strings.TrimPrefix(r.Path, "/")
+}
+
+// RouteTags are the tags of r's package groups followed by its own.
+func (self *Scanner) RouteTags(r *Route) []string {
+	_, tags := self.RouteGroup(r.Pkg)
+	for _, t := range r.Tags {
+		known := false
+		for _, v := range tags {
+			if v == t {
+				known = true
+				break
+			}
+		}
+		if !known {
+			tags = append(tags, t)
+		}
+	}
+	if len(tags) == 0 {
+		return nil
+	}
+	return tags
+}
+
+type Route struct {
+	ID, Method, Path string
+	Tags             []string
+	Pkg              string
+	Pos              token.Position
+	Remaining        *ast.CommentGroup
+}
+
+// Comment is the route comment without the swag:route line.
+func (self *Route) Comment() string {
+	if self.Remaining == nil {
+		return ""
+	}
+	lines := []string{}
+	for _, c := range self.Remaining.List {
+		lines = append(lines, strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(c.Text), "//")))
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+func parseRoute(fset *token.FileSet, lines []*ast.Comment) *Route {
+	route := Route{}
+
+	justMatched := false
+	for _, cmt := range lines {
+		txt := cmt.Text
+		for _, line := range strings.Split(txt, "\n") {
+			matches := rxRoute.FindStringSubmatch(line)
+			if len(matches) > 4 {
+				route.ID, route.Method, route.Path = matches[1], strings.ToUpper(matches[2]), matches[3]
+				route.Pos = fset.Position(cmt.Pos())
+				route.Tags = rxSpace.Split(matches[4], -1)
+				if len(matches[4]) == 0 {
+					route.Tags = nil
+				}
+				justMatched = true
+			} else if route.Method != "" {
+				if route.Remaining == nil {
+					route.Remaining = new(ast.CommentGroup)
+				}
+				if !justMatched || strings.TrimSpace(rxStripComments.ReplaceAllString(line, "")) != "" {
+					cc := new(ast.Comment)
+					cc.Slash = cmt.Slash
+					cc.Text = line
+					route.Remaining.List = append(route.Remaining.List, cc)
+					justMatched = false
+				}
+			}
+		}
+	}
+
+	return &route
+}
+
+type Decl struct {
+	ID   string
+	Name string
+	Code int
+
+	// PkgPath, PkgName, TypeName and Position identify the declared type.
+	// They stay set when the decl comes from the cache and the fields
+	// holding ast and go/types state are nil.
+	PkgPath  string
+	PkgName  string
+	TypeName string
+	Position token.Position
+
+	Comments *ast.CommentGroup
+	Type     *types.Named      `json:"-"`
+	Ident    *ast.Ident        `json:"-"`
+	Spec     *ast.TypeSpec     `json:"-"`
+	File     *ast.File         `json:"-"`
+	Pkg      *packages.Package `json:"-"`
+	HasReq   bool
+	HasAns   bool
+
+	frag *fragment
+}
+
+func parseDecl(pkg *packages.Package, file *ast.File, n node, gd *ast.GenDecl) []*Decl {
+	decls := []*Decl{}
+
+	for _, sp := range gd.Specs {
+		switch ts := sp.(type) {
+		case *ast.ValueSpec:
+			return nil
+		case *ast.ImportSpec:
+			return nil
+		case *ast.TypeSpec:
+
+			def, ok := pkg.TypesInfo.Defs[ts.Name]
+			if !ok {
+				continue
+			}
+
+			nt, isNamed := def.Type().(*types.Named)
+			if !isNamed {
+				continue
+			}
+
+			decls = append(decls, &Decl{
+				PkgPath:  pkg.PkgPath,
+				PkgName:  pkg.Name,
+				TypeName: ts.Name.Name,
+				Position: pkg.Fset.Position(ts.Pos()),
+				Comments: gd.Doc,
+				Type:     nt,
+				Ident:    ts.Name,
+				Spec:     ts,
+				File:     file,
+				Pkg:      pkg,
+			})
+		}
+	}
+
+	return decls
+}
+
+func (self *Decl) HasReqAnno() bool {
+	if self.HasReq {
+		return true
+	}
+	if self.Comments == nil {
+		return false
+	}
+	for _, cmt := range self.Comments.List {
+		for _, ln := range strings.Split(cmt.Text, "\n") {
+			matches := rxReq.FindStringSubmatch(ln)
+			if len(matches) > 0 {
+				self.ID = matches[1]
+				self.Name = matches[1]
+				self.HasReq = true
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (self *Decl) HasAnsAnno() bool {
+	if self.HasAns {
+		return true
+	}
+	if self.Comments == nil {
+		return false
+	}
+	for _, cmt := range self.Comments.List {
+		for _, ln := range strings.Split(cmt.Text, "\n") {
+			matches := rxAns.FindStringSubmatch(ln)
+			if len(matches) > 0 && validStatusCode(matches[2]) {
+				self.ID = matches[1]
+				self.Code, _ = strconv.Atoi(matches[2])
+				self.Name = matches[1] + "-" + matches[2]
+				self.HasAns = true
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (self *Decl) Pos() token.Position {
+	return self.Position
+}
@@ -0,0 +1,73 @@
+package generator
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"golang.org/x/tools/go/packages"
+)
+
+func TestRouteGroups(t *testing.T) {
+	pkgs := []*packages.Package{
+		testPackage(t, "testdata/groups/v1", "example.com/groups/v1"),
+		testPackage(t, "testdata/groups", "example.com/groups"),
+	}
+	scans, err := scanPackages(pkgs, nil, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	diags := new(Diagnostics)
+	s := merged(scans, diags)
+	if len(diags.List()) > 0 {
+		t.Fatalf("got diagnostics %s", diags.List()[0])
+	}
+
+	cases := []struct {
+		id   string
+		path string
+		tags []string
+	}{
+		{"health", "/api/health", []string{"api"}},
+		{"listUsers", "/api/v1/users", []string{"api", "v1", "users"}},
+		{"root", "/api/v1", []string{"api", "v1"}},
+	}
+	for _, c := range cases {
+		r, ok := s.Route(c.id)
+		if !ok {
+			t.Errorf("route %s: not found", c.id)
+			continue
+		}
+		if got := s.RoutePath(r); got != c.path {
+			t.Errorf("route %s: got path %s, want %s", c.id, got, c.path)
+		}
+		if got := s.RouteTags(r); !reflect.DeepEqual(got, c.tags) {
+			t.Errorf("route %s: got tags %q, want %q", c.id, got, c.tags)
+		}
+	}
+}
+
+func TestGroupConflict(t *testing.T) {
+	scans, err := scanPackages([]*packages.Package{testPackage(t, "testdata/groupconflict", "example.com/groupconflict")}, nil, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	diags := new(Diagnostics)
+	s := merged(scans, diags)
+
+	list := diags.List()
+	if len(list) != 1 || list[0].Code != CodeGroupConflict || list[0].Severity != SeverityError {
+		t.Fatalf("got diagnostics %v, want one %s error", list, CodeGroupConflict)
+	}
+	if got := list[0].Pos.Filename; filepath.Base(got) != "b.go" {
+		t.Errorf("got the conflict reported in %s, want b.go", got)
+	}
+	if len(list[0].Related) != 1 || filepath.Base(list[0].Related[0].Filename) != "a.go" {
+		t.Errorf("got related positions %v, want a.go", list[0].Related)
+	}
+
+	r, _ := s.Route("ping")
+	if got := s.RoutePath(r); got != "/a/ping" {
+		t.Errorf("got path %s, want the first group kept", got)
+	}
+}
@@ -0,0 +1,351 @@
+package generator
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/go-openapi/spec"
+)
+
+// valueError is what is wrong with a value, named like the fields of the
+// generated validation code, e.g. home.city or tags[2].
+type valueError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// valueChecker checks decoded JSON values against the schemas of a spec.
+// A null stands for a nil slice, map or pointer, so it is accepted for
+// arrays, objects and x-nullable schemas.
+type valueChecker struct {
+	sw *spec.Swagger
+
+	mu       sync.Mutex
+	patterns map[string]*regexp.Regexp
+}
+
+func newValueChecker(sw *spec.Swagger) *valueChecker {
+	return &valueChecker{sw: sw, patterns: map[string]*regexp.Regexp{}}
+}
+
+func (self *valueChecker) check(schema *spec.Schema, value interface{}, path string, errs *[]valueError) {
+	self.checkSeen(schema, value, path, errs, map[string]bool{})
+}
+
+// checkSeen checks value against schema; seen holds the definitions already
+// resolved for this very value, to stop at reference cycles. Values nested
+// in it start over, recursive definitions end where the value does.
+func (self *valueChecker) checkSeen(schema *spec.Schema, value interface{}, path string, errs *[]valueError, seen map[string]bool) {
+	fail := func(format string, args ...interface{}) {
+		*errs = append(*errs, valueError{Field: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	for schema != nil && schema.Ref.String() != "" {
+		name := refName(schema)
+		def, ok := self.sw.Definitions[name]
+		if !ok || seen[name] {
+			return
+		}
+		seen[name] = true
+		defer delete(seen, name)
+		schema = &def
+	}
+	if schema == nil {
+		return
+	}
+	for i := range schema.AllOf {
+		self.checkSeen(&schema.AllOf[i], value, path, errs, seen)
+	}
+
+	tpe := ""
+	if len(schema.Type) > 0 {
+		tpe = schema.Type[0]
+	}
+	if value == nil {
+		nullable, _ := schema.Extensions.GetBool("x-nullable")
+		if !nullable && !schema.Nullable && tpe != "" && tpe != "array" && tpe != "object" {
+			fail("must not be null")
+		}
+		return
+	}
+
+	switch tpe {
+	case "object":
+		if _, ok := value.(map[string]interface{}); !ok {
+			fail("must be an object")
+			return
+		}
+	case "array":
+		if _, ok := value.([]interface{}); !ok {
+			fail("must be an array")
+			return
+		}
+	case "string":
+		if _, ok := value.(string); !ok {
+			fail("must be a string")
+			return
+		}
+	case "integer":
+		if f, ok := value.(float64); !ok || f != math.Trunc(f) {
+			fail("must be an integer")
+			return
+		}
+	case "number":
+		if _, ok := value.(float64); !ok {
+			fail("must be a number")
+			return
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			fail("must be a boolean")
+			return
+		}
+	}
+
+	if len(schema.Enum) > 0 {
+		found, names := false, []string{}
+		for _, e := range schema.Enum {
+			found = found || jsonEqual(e, value)
+			names = append(names, fmt.Sprint(e))
+		}
+		if !found {
+			fail("must be one of %s", strings.Join(names, ", "))
+		}
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for _, name := range schema.Required {
+			if _, ok := v[name]; !ok {
+				*errs = append(*errs, valueError{Field: fieldPath(path, name), Message: "is required"})
+			}
+		}
+		for _, name := range sortedMapKeys(v) {
+			if prop, ok := schema.Properties[name]; ok {
+				self.checkSeen(&prop, v[name], fieldPath(path, name), errs, map[string]bool{})
+			} else if schema.AdditionalProperties != nil && schema.AdditionalProperties.Schema != nil {
+				self.checkSeen(schema.AdditionalProperties.Schema, v[name], fieldPath(path, name), errs, map[string]bool{})
+			}
+		}
+	case []interface{}:
+		if schema.MinItems != nil && int64(len(v)) < *schema.MinItems {
+			fail("must have at least %d items", *schema.MinItems)
+		}
+		if schema.MaxItems != nil && int64(len(v)) > *schema.MaxItems {
+			fail("must have at most %d items", *schema.MaxItems)
+		}
+		if schema.UniqueItems {
+			seenItems := map[string]bool{}
+			for _, item := range v {
+				b, _ := json.Marshal(item)
+				if seenItems[string(b)] {
+					fail("must not contain duplicates")
+					break
+				}
+				seenItems[string(b)] = true
+			}
+		}
+		if schema.Items != nil && schema.Items.Schema != nil {
+			for i, item := range v {
+				self.checkSeen(schema.Items.Schema, item, path+"["+strconv.Itoa(i)+"]", errs, map[string]bool{})
+			}
+		}
+	case string:
+		n := int64(utf8.RuneCountInString(v))
+		if schema.MinLength != nil && n < *schema.MinLength {
+			fail("must be at least %d characters long", *schema.MinLength)
+		}
+		if schema.MaxLength != nil && n > *schema.MaxLength {
+			fail("must be at most %d characters long", *schema.MaxLength)
+		}
+		if schema.Pattern != "" {
+			if rx := self.pattern(schema.Pattern); rx != nil && !rx.MatchString(v) {
+				fail("must match %s", schema.Pattern)
+			}
+		}
+		if check, ok := formatChecks[schema.Format]; ok && !validFormat(schema.Format, v) {
+			fail("%s", check.message)
+		}
+	case float64:
+		bound := func(n *float64, exclusive bool, outside func(a, b float64) bool, message, exclusiveMessage string) {
+			if n == nil {
+				return
+			}
+			if exclusive {
+				message = exclusiveMessage
+			}
+			if outside(v, *n) || exclusive && v == *n {
+				fail("%s %s", message, formatNumber(*n))
+			}
+		}
+		bound(schema.Minimum, schema.ExclusiveMinimum, func(a, b float64) bool { return a < b }, "must be at least", "must be greater than")
+		bound(schema.Maximum, schema.ExclusiveMaximum, func(a, b float64) bool { return a > b }, "must be at most", "must be less than")
+		if m := schema.MultipleOf; m != nil && *m != 0 && math.Mod(v, *m) != 0 {
+			fail("must be a multiple of %s", formatNumber(*m))
+		}
+	}
+}
+
+func (self *valueChecker) pattern(pattern string) *regexp.Regexp {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	rx, ok := self.patterns[pattern]
+	if !ok {
+		// patterns Go cannot compile are not checked
+		rx, _ = regexp.Compile(pattern)
+		self.patterns[pattern] = rx
+	}
+	return rx
+}
+
+// paramSchema is the schema of a non-body parameter, or of a header.
+func paramSchema(simple spec.SimpleSchema, v spec.CommonValidations, items *spec.Items) *spec.Schema {
+	schema := new(spec.Schema).Typed(simple.Type, simple.Format)
+	schema.Maximum, schema.ExclusiveMaximum = v.Maximum, v.ExclusiveMaximum
+	schema.Minimum, schema.ExclusiveMinimum = v.Minimum, v.ExclusiveMinimum
+	schema.MaxLength, schema.MinLength, schema.Pattern = v.MaxLength, v.MinLength, v.Pattern
+	schema.MaxItems, schema.MinItems, schema.UniqueItems = v.MaxItems, v.MinItems, v.UniqueItems
+	schema.MultipleOf, schema.Enum = v.MultipleOf, v.Enum
+	if items != nil {
+		schema.Items = &spec.SchemaOrArray{Schema: paramSchema(items.SimpleSchema, items.CommonValidations, items.Items)}
+	}
+	return schema
+}
+
+// paramValue parses the raw values of a non-body parameter into what
+// its schema checks, or reports why it cannot.
+func paramValue(simple spec.SimpleSchema, items *spec.Items, raw []string) (interface{}, string) {
+	if simple.Type == "array" {
+		values := raw
+		if simple.CollectionFormat != "multi" && len(raw) > 0 {
+			sep := map[string]string{"ssv": " ", "tsv": "\t", "pipes": "|"}[simple.CollectionFormat]
+			if sep == "" {
+				sep = ","
+			}
+			values = strings.Split(raw[0], sep)
+		}
+		list := []interface{}{}
+		for _, s := range values {
+			if items == nil {
+				list = append(list, s)
+				continue
+			}
+			v, msg := paramValue(items.SimpleSchema, items.Items, []string{s})
+			if msg != "" {
+				return nil, msg
+			}
+			list = append(list, v)
+		}
+		return list, ""
+	}
+
+	s := ""
+	if len(raw) > 0 {
+		s = raw[0]
+	}
+	switch simple.Type {
+	case "integer":
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return nil, "must be an integer"
+		}
+		return float64(n), ""
+	case "number":
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return nil, "must be a number"
+		}
+		return f, ""
+	case "boolean":
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return nil, "must be a boolean"
+		}
+		return b, ""
+	}
+	return s, ""
+}
+
+var (
+	rxUUID     = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+	rxHostname = regexp.MustCompile(`^[a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?(\.[a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)*$`)
+)
+
+// validFormat checks s like the functions of formatChecks do in generated
+// code; other formats are not checked.
+func validFormat(format, s string) bool {
+	switch format {
+	case "date":
+		_, err := time.Parse("2006-01-02", s)
+		return err == nil
+	case "date-time":
+		_, err := time.Parse(time.RFC3339, s)
+		return err == nil
+	case "email":
+		a, err := mail.ParseAddress(s)
+		return err == nil && a.Address == s
+	case "hostname":
+		return len(s) <= 253 && rxHostname.MatchString(s)
+	case "ipv4":
+		return net.ParseIP(s) != nil && !strings.Contains(s, ":")
+	case "ipv6":
+		return net.ParseIP(s) != nil && strings.Contains(s, ":")
+	case "uri":
+		u, err := url.Parse(s)
+		return err == nil && u.IsAbs()
+	case "uuid":
+		return rxUUID.MatchString(s)
+	}
+	return true
+}
+
+func fieldPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// jsonEqual compares values the way they compare as JSON, so that enum
+// values of the spec match decoded numbers and 1 equals 1.0.
+func jsonEqual(a, b interface{}) bool {
+	switch av := a.(type) {
+	case map[string]interface{}:
+		if bv, ok := b.(map[string]interface{}); ok {
+			if len(av) != len(bv) {
+				return false
+			}
+			for k, v := range av {
+				if w, ok := bv[k]; !ok || !jsonEqual(v, w) {
+					return false
+				}
+			}
+			return true
+		}
+	case []interface{}:
+		if bv, ok := b.([]interface{}); ok {
+			if len(av) != len(bv) {
+				return false
+			}
+			for i := range av {
+				if !jsonEqual(av[i], bv[i]) {
+					return false
+				}
+			}
+			return true
+		}
+	}
+	ab, aerr := json.Marshal(normalizeNumber(a))
+	bb, berr := json.Marshal(normalizeNumber(b))
+	return aerr == nil && berr == nil && string(ab) == string(bb)
+}
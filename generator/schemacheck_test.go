@@ -0,0 +1,75 @@
+package generator
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/go-openapi/spec"
+)
+
+func TestValueChecker(t *testing.T) {
+	sw := new(spec.Swagger)
+	if err := json.Unmarshal([]byte(`{
+  "definitions": {
+    "Loop": {"type": "string", "allOf": [{"$ref": "#/definitions/Loop"}]},
+    "Node": {
+      "type": "object",
+      "required": ["id"],
+      "properties": {
+        "id": {"type": "integer"},
+        "next": {"$ref": "#/definitions/Node"}
+      }
+    }
+  }
+}`), sw); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name, schema, value string
+		errors              []string
+	}{
+		{"integer", `{"type": "integer"}`, `3`, nil},
+		{"integer with a fraction", `{"type": "integer"}`, `1.5`, []string{"v: must be an integer"}},
+		{"string for a number", `{"type": "number"}`, `"1"`, []string{"v: must be a number"}},
+		{"null", `{"type": "string"}`, `null`, []string{"v: must not be null"}},
+		{"nullable", `{"type": "string", "x-nullable": true}`, `null`, nil},
+		{"null array", `{"type": "array"}`, `null`, nil},
+		{"enum numbers compare by value", `{"type": "number", "enum": [1, 2.5]}`, `1.0`, nil},
+		{"enum objects compare structurally", `{"type": "object", "enum": [{"a": [1, 2]}]}`, `{"a": [1.0, 2]}`, nil},
+		{"not in enum", `{"type": "string", "enum": ["a", "b"]}`, `"c"`, []string{"v: must be one of a, b"}},
+		{"exclusive bounds", `{"type": "number", "minimum": 1, "exclusiveMinimum": true, "maximum": 5}`, `1`, []string{"v: must be greater than 1"}},
+		{"multiple of", `{"type": "integer", "multipleOf": 3}`, `7`, []string{"v: must be a multiple of 3"}},
+		{"string length counts runes", `{"type": "string", "maxLength": 2}`, `"äö"`, nil},
+		{"pattern", `{"type": "string", "pattern": "^a+$"}`, `"ab"`, []string{"v: must match ^a+$"}},
+		{"unusable pattern is not checked", `{"type": "string", "pattern": "(?<=a)b"}`, `"b"`, nil},
+		{"format", `{"type": "string", "format": "date-time"}`, `"yesterday"`, []string{"v: must be an RFC 3339 date and time"}},
+		{"unique items", `{"type": "array", "uniqueItems": true, "minItems": 3}`, `[1, 1]`, []string{"v: must have at least 3 items", "v: must not contain duplicates"}},
+		{"items", `{"type": "array", "items": {"type": "string"}}`, `["a", 2]`, []string{"v[1]: must be a string"}},
+		{"additional properties", `{"type": "object", "additionalProperties": {"type": "boolean"}}`, `{"a": true, "b": 0}`, []string{"v.b: must be a boolean"}},
+		{"reference cycle", `{"$ref": "#/definitions/Loop"}`, `1`, []string{"v: must be a string"}},
+		{"recursive definitions", `{"$ref": "#/definitions/Node"}`, `{"id": 1, "next": {"next": {"id": "2"}}}`, []string{"v.next.id: is required", "v.next.next.id: must be an integer"}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			schema := new(spec.Schema)
+			if err := json.Unmarshal([]byte(c.schema), schema); err != nil {
+				t.Fatal(err)
+			}
+			var value interface{}
+			if err := json.Unmarshal([]byte(c.value), &value); err != nil {
+				t.Fatal(err)
+			}
+			errs := []valueError{}
+			newValueChecker(sw).check(schema, value, "v", &errs)
+			got := []string{}
+			for _, e := range errs {
+				got = append(got, e.Field+": "+e.Message)
+			}
+			if len(got) != len(c.errors) || len(got) > 0 && !reflect.DeepEqual(got, c.errors) {
+				t.Errorf("got %q, want %q", got, c.errors)
+			}
+		})
+	}
+}
@@ -0,0 +1,176 @@
+package generator
+
+import (
+	"bytes"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// metaLoadMode is enough to walk the import graph and find the files of
+// every package, without parsing or type checking anything.
+const metaLoadMode = packages.NeedName | packages.NeedFiles | packages.NeedImports | packages.NeedDeps
+
+// packageFilter decides which packages are scanned for annotations. With
+// no include patterns only the main module is scanned.
+type packageFilter struct {
+	include []*regexp.Regexp
+	exclude []*regexp.Regexp
+	roots   map[string]bool
+}
+
+func newPackageFilter(dir string, include, exclude []string, roots []*packages.Package) *packageFilter {
+	f := packageFilter{roots: map[string]bool{}}
+	for _, p := range roots {
+		f.roots[p.PkgPath] = true
+	}
+	if len(include) == 0 {
+		if mod := mainModule(dir); mod != "" {
+			include = []string{mod + "/..."}
+		}
+	}
+	for _, p := range include {
+		f.include = append(f.include, patternRegexp(p))
+	}
+	for _, p := range exclude {
+		f.exclude = append(f.exclude, patternRegexp(p))
+	}
+	return &f
+}
+
+func (self *packageFilter) match(pkgPath string) bool {
+	for _, rx := range self.exclude {
+		if rx.MatchString(pkgPath) {
+			return false
+		}
+	}
+	if len(self.include) == 0 {
+		// outside of a module, stick to what was asked for
+		return self.roots[pkgPath]
+	}
+	for _, rx := range self.include {
+		if rx.MatchString(pkgPath) {
+			return true
+		}
+	}
+	return false
+}
+
+// patternRegexp compiles a go package pattern, where ... matches any
+// string and a trailing /... also matches the parent itself.
+func patternRegexp(pattern string) *regexp.Regexp {
+	rx := regexp.QuoteMeta(pattern)
+	rx = strings.Replace(rx, `\.\.\.`, `.*`, -1)
+	if strings.HasSuffix(rx, `/.*`) {
+		rx = strings.TrimSuffix(rx, `/.*`) + `(/.*)?`
+	}
+	return regexp.MustCompile(`^` + rx + `$`)
+}
+
+// goModFile is the go.mod governing dir, empty outside of a module.
+func goModFile(dir string) string {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return ""
+	}
+	for {
+		name := filepath.Join(dir, "go.mod")
+		if _, err := os.Stat(name); err == nil {
+			return name
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// mainModule is the module path declared by the go.mod governing dir.
+func mainModule(dir string) string {
+	name := goModFile(dir)
+	if name == "" {
+		return ""
+	}
+	b, err := ioutil.ReadFile(name)
+	if err != nil {
+		return ""
+	}
+	for _, line := range strings.Split(string(b), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) >= 2 && fields[0] == "module" {
+			if path, err := strconv.Unquote(fields[1]); err == nil {
+				return path
+			}
+			return fields[1]
+		}
+	}
+	return ""
+}
+
+// selectPackages walks the import graph of roots and returns the packages
+// passing the filter, in path order.
+func selectPackages(roots []*packages.Package, filter *packageFilter) []*packages.Package {
+	selected := []*packages.Package{}
+	seen := map[string]bool{}
+
+	var walk func(pkg *packages.Package)
+	walk = func(pkg *packages.Package) {
+		if seen[pkg.PkgPath] {
+			return
+		}
+		seen[pkg.PkgPath] = true
+
+		if filter.match(pkg.PkgPath) {
+			selected = append(selected, pkg)
+		}
+		for _, imp := range pkg.Imports {
+			walk(imp)
+		}
+	}
+	for _, pkg := range roots {
+		walk(pkg)
+	}
+
+	sort.Slice(selected, func(i, j int) bool { return selected[i].PkgPath < selected[j].PkgPath })
+	return selected
+}
+
+// needsScan is the cheap check run before type checking: it only parses
+// files that contain the annotation prefix at all, and only looks at their
+// comments. Packages with load errors are kept so the errors get reported.
+func needsScan(pkg *packages.Package) bool {
+	if len(pkg.Errors) > 0 {
+		return true
+	}
+	fset := token.NewFileSet()
+	for _, name := range pkg.GoFiles {
+		src, err := ioutil.ReadFile(name)
+		if err != nil {
+			return true
+		}
+		if !bytes.Contains(src, []byte("swag:")) {
+			continue
+		}
+		file, err := parser.ParseFile(fset, name, src, parser.ParseComments)
+		if err != nil {
+			return true
+		}
+		for _, cg := range file.Comments {
+			for _, c := range cg.List {
+				if rxSwag.MatchString(c.Text) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,102 @@
+package generator
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"golang.org/x/tools/go/packages"
+)
+
+func TestPatternRegexp(t *testing.T) {
+	cases := []struct {
+		pattern string
+		match   []string
+		nomatch []string
+	}{
+		{"example.com/api", []string{"example.com/api"}, []string{"example.com/api/v2", "example.com/apis", "example.com", "xexample.com/api"}},
+		{"example.com/api/...", []string{"example.com/api", "example.com/api/v2", "example.com/api/v2/users"}, []string{"example.com/apis", "example.com"}},
+		{"example.com/...", []string{"example.com", "example.com/api"}, []string{"example.community", "example.org/api"}},
+		{"example.com/.../internal", []string{"example.com/a/internal", "example.com/a/b/internal"}, []string{"example.com/internal/x", "example.com/a/internals"}},
+		{"example.com/api...", []string{"example.com/api", "example.com/apis", "example.com/api/v2"}, []string{"example.com/ap"}},
+		{"...", []string{"", "fmt", "example.com/api"}, nil},
+		{"example.com/a+b", []string{"example.com/a+b"}, []string{"example.com/aab"}},
+	}
+	for _, c := range cases {
+		rx := patternRegexp(c.pattern)
+		for _, path := range c.match {
+			if !rx.MatchString(path) {
+				t.Errorf("%s does not match %q", c.pattern, path)
+			}
+		}
+		for _, path := range c.nomatch {
+			if rx.MatchString(path) {
+				t.Errorf("%s matches %q", c.pattern, path)
+			}
+		}
+	}
+}
+
+func TestPackageFilter(t *testing.T) {
+	roots := []*packages.Package{{PkgPath: "example.com/api"}}
+	cases := []struct {
+		name             string
+		include, exclude []string
+		match            []string
+		nomatch          []string
+	}{
+		{
+			name:    "main module by default",
+			match:   []string{"github.com/xucx/go2swag", "github.com/xucx/go2swag/generator"},
+			nomatch: []string{"example.com/api", "github.com/go-openapi/spec"},
+		},
+		{
+			name:    "include",
+			include: []string{"example.com/...", "fmt"},
+			match:   []string{"example.com/api", "example.com/api/v2", "fmt"},
+			nomatch: []string{"github.com/xucx/go2swag", "net/http"},
+		},
+		{
+			name:    "exclude wins",
+			include: []string{"example.com/..."},
+			exclude: []string{"example.com/api/internal/...", "example.com/gen"},
+			match:   []string{"example.com/api", "example.com/generated"},
+			nomatch: []string{"example.com/api/internal", "example.com/api/internal/db", "example.com/gen"},
+		},
+		{
+			name:    "exclude from the main module",
+			exclude: []string{"github.com/xucx/go2swag/generator/..."},
+			match:   []string{"github.com/xucx/go2swag", "github.com/xucx/go2swag/conform"},
+			nomatch: []string{"github.com/xucx/go2swag/generator"},
+		},
+	}
+	for _, c := range cases {
+		f := newPackageFilter(".", c.include, c.exclude, roots)
+		for _, path := range c.match {
+			if !f.match(path) {
+				t.Errorf("%s: %s is not scanned", c.name, path)
+			}
+		}
+		for _, path := range c.nomatch {
+			if f.match(path) {
+				t.Errorf("%s: %s is scanned", c.name, path)
+			}
+		}
+	}
+}
+
+func TestPackageFilterOutsideModule(t *testing.T) {
+	dir, err := ioutil.TempDir("", "go2swag-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	f := newPackageFilter(dir, nil, nil, []*packages.Package{{PkgPath: "api"}})
+	if !f.match("api") {
+		t.Error("the package asked for is not scanned")
+	}
+	if f.match("api/v2") || f.match("fmt") {
+		t.Error("packages not asked for are scanned")
+	}
+}
@@ -0,0 +1,391 @@
+package generator
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"unicode"
+
+	"github.com/go-openapi/spec"
+)
+
+// GoServer renders Go code of package pkg serving the routes of the scan:
+// per package declaring routes an interface with a method per route, taking
+// the swag:req type and returning one of the swag:ans types, and an
+// http.Handler binding requests to them without reflection.
+func GoServer(sw *spec.Swagger, scan *Scanner, pkg string, diags *Diagnostics) ([]byte, error) {
+	imports := newGoImports("context", "errors", "io", "json", "http", "strconv", "strings",
+		"handler", "route", "routes", "paramsKey", "requestKey", "errNoResponse", "badRequest", "decodeBody", "writeJSON", "serverSplitPath", "serverMatchPath", "serverMatchSegment",
+		"h", "w", "r", "s", "v", "x", "req", "res", "err")
+	routes := goRoutes(scan, imports, diags)
+
+	used := map[string]bool{"ErrorHandler": true}
+	groups := []*serverGroup{}
+	byPkg := map[string]*serverGroup{}
+	for i := range routes {
+		r := &routes[i]
+		g, ok := byPkg[r.Pkg]
+		if !ok {
+			name := exportedName(path.Base(r.Pkg))
+			if name == "" {
+				name = "API"
+			}
+			base := name
+			for n := 2; used[name] || used[name+"Server"]; n++ {
+				name = base + strconv.Itoa(n)
+			}
+			used[name], used[name+"Server"] = true, true
+			g = &serverGroup{Field: name, Interface: name + "Server", Pkg: r.Pkg}
+			byPkg[r.Pkg] = g
+			groups = append(groups, g)
+		}
+
+		for _, fields := range []struct {
+			list []goField
+			what string
+		}{{r.PathFields, "path parameter"}, {r.Query, "query parameter"}, {r.Header, "header"}} {
+			for j := range fields.list {
+				f := &fields.list[j]
+				bind, ok := imports.bindCode(f, fmt.Sprintf("badRequest(w, %q, err)\nreturn", fields.what+" "+f.Name))
+				if !ok {
+					diags.Warnf(r.Pos, CodeCodegen, "route %s: %s %s cannot be parsed into %s, it is left unset", r.Name, fields.what, f.Name, f.Field)
+				}
+				f.Bind = strings.TrimSpace(bind)
+			}
+		}
+		g.Routes = append(g.Routes, serverRoute{goRoute: *r, Group: g.Field})
+	}
+
+	table := []serverRoute{}
+	for _, g := range groups {
+		table = append(table, g.Routes...)
+	}
+	sort.SliceStable(table, func(i, j int) bool { return morePrecise(table[i].Path, table[j].Path) })
+
+	strconvUsed := false
+	for _, r := range table {
+		for _, f := range append(append(append([]goField{}, r.PathFields...), r.Query...), r.Header...) {
+			strconvUsed = strconvUsed || strings.Contains(f.Bind, "strconv.")
+		}
+	}
+
+	var buf bytes.Buffer
+	err := goServerTemplate.Execute(&buf, map[string]interface{}{
+		"Package":     pkg,
+		"BasePath":    strings.TrimSuffix(sw.BasePath, "/"),
+		"PathHelpers": goPathHelpers("server"),
+		"Imports":     imports.list(),
+		"Strconv":     strconvUsed,
+		"Groups":      groups,
+		"Table":       table,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return format.Source(buf.Bytes())
+}
+
+type serverGroup struct {
+	// Field is the name of the group in Servers.
+	Field, Interface string
+	Pkg              string
+	Routes           []serverRoute
+}
+
+type serverRoute struct {
+	goRoute
+	Group string
+}
+
+// morePrecise orders literal path segments before parameters, so that
+// /users/me is tried before /users/{id}, and paths alphabetically otherwise.
+func morePrecise(a, b string) bool {
+	as, bs := strings.Split(a, "/"), strings.Split(b, "/")
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		ap := strings.HasPrefix(as[i], "{") || strings.HasPrefix(as[i], ":")
+		bp := strings.HasPrefix(bs[i], "{") || strings.HasPrefix(bs[i], ":")
+		switch {
+		case ap != bp:
+			return bp
+		case !ap && as[i] != bs[i]:
+			return as[i] < bs[i]
+		}
+	}
+	return len(as) < len(bs)
+}
+
+// bindCode is the code parsing the parameter in s into the request field,
+// running onError when it fails, or false when the field type cannot be
+// named or parsed.
+func (self *goImports) bindCode(f *goField, onError string) (string, bool) {
+	t := f.Type
+	target := "req." + f.Field
+
+	fail := "if err != nil {\n" + onError + "\n}\n"
+	if t.Text && !t.Pointer {
+		return fmt.Sprintf("err := %s.UnmarshalText([]byte(s))\n%s", target, fail), true
+	}
+
+	// only qualified when used, it may add an import
+	typeName := t.Basic
+	if t.Name != "" {
+		if t.PkgName == "main" || !unicode.IsUpper([]rune(t.Name)[0]) {
+			return "", false
+		}
+		typeName = self.qualified(t.PkgPath, t.PkgName, t.Name)
+	}
+	if t.Text {
+		return fmt.Sprintf("%s = new(%s)\nerr := %s.UnmarshalText([]byte(s))\n%s", target, typeName, target, fail), true
+	}
+
+	code, valueType, value := "", "", "v"
+	switch t.Basic {
+	case "string":
+		valueType, value = "string", "s"
+	case "bool":
+		code, valueType = "v, err := strconv.ParseBool(s)\n"+fail, "bool"
+	case "int", "int8", "int16", "int32", "int64":
+		code, valueType = fmt.Sprintf("v, err := strconv.ParseInt(s, 10, %d)\n", bitSize(t.Basic))+fail, "int64"
+	case "uint", "uint8", "uint16", "uint32", "uint64", "uintptr":
+		code, valueType = fmt.Sprintf("v, err := strconv.ParseUint(s, 10, %d)\n", bitSize(t.Basic))+fail, "uint64"
+	case "float32", "float64":
+		code, valueType = fmt.Sprintf("v, err := strconv.ParseFloat(s, %d)\n", bitSize(t.Basic))+fail, "float64"
+	default:
+		return "", false
+	}
+
+	if typeName != valueType {
+		value = typeName + "(" + value + ")"
+	}
+	if t.Pointer {
+		return code + fmt.Sprintf("x := %s\n%s = &x\n", value, target), true
+	}
+	return code + fmt.Sprintf("%s = %s\n", target, value), true
+}
+
+// bitSize is the strconv bit size of a basic type, 0 for int and uint.
+func bitSize(basic string) int {
+	switch basic {
+	case "int8", "uint8":
+		return 8
+	case "int16", "uint16":
+		return 16
+	case "int32", "uint32", "float32":
+		return 32
+	case "int64", "uint64", "uintptr", "float64":
+		return 64
+	}
+	return 0
+}
+
+var goServerTemplate = template.Must(template.New("").Funcs(template.FuncMap{
+	"comment": func(s string) string {
+		return strings.Replace(s, "\n", "\n// ", -1)
+	},
+}).Parse(`// Code generated by go2swag. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+{{- if .Strconv}}
+	"strconv"{{end}}
+	"strings"
+{{range .Imports}}
+	{{.Alias}} {{printf "%q" .Path}}{{end}}
+)
+
+{{range .Groups}}
+// {{.Interface}} serves the routes declared in {{.Pkg}}.
+type {{.Interface}} interface {
+{{- range .Routes}}
+	// {{.Name}} handles {{.Method}} {{.Path}}.{{if .Comment}}
+	//
+	// {{comment .Comment}}{{end}}
+	{{.Name}}(ctx context.Context{{if .Request}}, req *{{.Request}}{{end}}) (*{{.Name}}Response, error)
+{{end -}}
+}
+{{end}}
+// Servers are what NewHandler dispatches to. Routes of a nil server answer
+// 501 Not Implemented.
+type Servers struct {
+{{- range .Groups}}
+	{{.Field}} {{.Interface}}{{end}}
+	// ErrorHandler writes errors returned by the servers, a plain 500 if nil.
+	ErrorHandler func(w http.ResponseWriter, r *http.Request, err error)
+}
+{{range .Table}}{{$route := .}}
+// {{.Name}}Response is the response of {{.Name}}; set the field of the
+// status to send{{if not .Responses}}, none is declared so the response is 204 No Content{{end}}.
+type {{.Name}}Response struct {
+	// Header is added to the response.
+	Header http.Header
+{{- range .Responses}}
+	Status{{.Status}} *{{.Type}}{{end}}
+}
+{{range .Responses}}
+// {{$route.Name}}{{.Status}} responds to {{$route.Name}} with status {{.Status}}.
+func {{$route.Name}}{{.Status}}(body *{{.Type}}) *{{$route.Name}}Response {
+	return &{{$route.Name}}Response{Status{{.Status}}: body}
+}
+{{end}}{{end}}
+// NewHandler routes requests to the servers, binding path, query, header
+// and body parameters into the request types and encoding the responses
+// with their status.
+func NewHandler(s Servers) http.Handler {
+	return &handler{servers: s}
+}
+
+// PathParam is a path parameter of the request being served.
+func PathParam(ctx context.Context, name string) string {
+	params, _ := ctx.Value(paramsKey{}).(map[string]string)
+	return params[name]
+}
+
+// Request is the request being served, e.g. for cookies.
+func Request(ctx context.Context) *http.Request {
+	r, _ := ctx.Value(requestKey{}).(*http.Request)
+	return r
+}
+
+type paramsKey struct{}
+
+type requestKey struct{}
+
+var errNoResponse = errors.New("the server set no response")
+
+type handler struct {
+	servers Servers
+}
+
+type route struct {
+	method string
+	path   []string
+	serve  func(h *handler, w http.ResponseWriter, r *http.Request)
+}
+
+// routes are ordered with literal path segments before parameters.
+var routes = []route{
+{{- range .Table}}
+	{ {{- printf "%q" .Method}}, serverSplitPath({{printf "%q" (print $.BasePath .Path)}}), (*handler).serve{{.Name}}},{{end}}
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := serverSplitPath(r.URL.Path)
+	allowed := []string{}
+	seen := map[string]bool{}
+	for _, rt := range routes {
+		params, ok := serverMatchPath(rt.path, path)
+		if !ok {
+			continue
+		}
+		if rt.method != r.Method {
+			if !seen[rt.method] {
+				seen[rt.method] = true
+				allowed = append(allowed, rt.method)
+			}
+			continue
+		}
+		ctx := context.WithValue(r.Context(), paramsKey{}, params)
+		ctx = context.WithValue(ctx, requestKey{}, r)
+		rt.serve(h, w, r.WithContext(ctx))
+		return
+	}
+	if len(allowed) > 0 {
+		w.Header().Set("Allow", strings.Join(allowed, ", "))
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	http.NotFound(w, r)
+}
+{{range .Table}}
+func (h *handler) serve{{.Name}}(w http.ResponseWriter, r *http.Request) {
+	if h.servers.{{.Group}} == nil {
+		http.Error(w, http.StatusText(http.StatusNotImplemented), http.StatusNotImplemented)
+		return
+	}
+{{- if .Request}}
+	req := new({{.Request}})
+{{- if .Body}}
+	if err := decodeBody(r, req); err != nil {
+		badRequest(w, "body", err)
+		return
+	}{{end}}
+{{- range .PathFields}}{{if .Bind}}
+	if s := PathParam(r.Context(), {{printf "%q" .Name}}); s != "" {
+		{{.Bind}}
+	}{{end}}{{end}}
+{{- range .Query}}{{if .Bind}}
+	if s := r.URL.Query().Get({{printf "%q" .Name}}); s != "" {
+		{{.Bind}}
+	}{{end}}{{end}}
+{{- range .Header}}{{if .Bind}}
+	if s := r.Header.Get({{printf "%q" .Name}}); s != "" {
+		{{.Bind}}
+	}{{end}}{{end}}
+{{end}}
+	res, err := h.servers.{{.Group}}.{{.Name}}(r.Context(){{if .Request}}, req{{end}})
+	if err != nil {
+		h.fail(w, r, err)
+		return
+	}
+	if res == nil {
+		res = new({{.Name}}Response)
+	}
+{{- if .Responses}}
+	switch {
+{{- range .Responses}}
+	case res.Status{{.Status}} != nil:
+		writeJSON(w, res.Header, {{.Status}}, res.Status{{.Status}}){{end}}
+	default:
+		h.fail(w, r, errNoResponse)
+	}{{else}}
+	writeJSON(w, res.Header, http.StatusNoContent, nil){{end}}
+}
+{{end}}
+func (h *handler) fail(w http.ResponseWriter, r *http.Request, err error) {
+	if h.servers.ErrorHandler != nil {
+		h.servers.ErrorHandler(w, r, err)
+		return
+	}
+	http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+}
+
+func badRequest(w http.ResponseWriter, what string, err error) {
+	http.Error(w, "invalid "+what+": "+err.Error(), http.StatusBadRequest)
+}
+
+// decodeBody reads a JSON body into v, an empty body leaves v alone.
+func decodeBody(r *http.Request, v interface{}) error {
+	if r.Body == nil {
+		return nil
+	}
+	err := json.NewDecoder(r.Body).Decode(v)
+	if err == io.EOF {
+		return nil
+	}
+	return err
+}
+
+func writeJSON(w http.ResponseWriter, header http.Header, status int, v interface{}) {
+	for k, values := range header {
+		w.Header()[k] = values
+	}
+	if v == nil {
+		w.WriteHeader(status)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+{{.PathHelpers}}`))
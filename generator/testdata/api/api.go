@@ -0,0 +1,53 @@
+package api
+
+import "time"
+
+// swag:route getUser GET /users/{id} users
+// Get a user
+
+// swag:route createUser POST /users users
+// Create a user
+
+// swag:route getFile GET /files/{name}.json files
+// Get a file
+
+// swag:req getUser
+type GetUserReq struct {
+	ID      string `json:"id"`
+	Verbose bool   `json:"verbose"`
+	Trace   string `json:"-" header:"X-Trace"`
+}
+
+// swag:req createUser
+// body
+type CreateUserReq struct {
+	Name  string   `json:"name" validate:"required,max=64" pattern:"^[a-z]+$"`
+	Age   int      `json:"age,omitempty" validate:"min=1"`
+	Email *string  `json:"email,omitempty" validate:"email"`
+	Tags  []string `json:"tags,omitempty" validate:"max=3"`
+}
+
+// swag:req getFile
+type GetFileReq struct {
+	Name  string `json:"name"`
+	Limit int    `json:"limit" validate:"min=1"`
+}
+
+// swag:ans getUser 200
+// ok
+type User struct {
+	Name    string    `json:"name"`
+	Created time.Time `json:"created"`
+}
+
+// swag:ans createUser 201
+// created
+type Created struct {
+	ID string `json:"id"`
+}
+
+// swag:ans getFile 200
+// the file
+type File struct {
+	Name string `json:"name"`
+}
@@ -0,0 +1,31 @@
+package conflict
+
+// swag:route createUser POST /users users
+// Create a user
+
+// swag:route createAdmin POST /users admins
+// Create an admin
+
+// swag:req createUser
+// user
+type CreateUserReq struct {
+	Name string `json:"name"`
+}
+
+// swag:req createAdmin
+// admin
+type CreateAdminReq struct {
+	Level int `json:"level"`
+}
+
+// swag:ans createUser 201
+// created
+type Created struct {
+	ID string `json:"id"`
+}
+
+// swag:ans createAdmin 202
+// accepted
+type Accepted struct {
+	Ticket string `json:"ticket"`
+}
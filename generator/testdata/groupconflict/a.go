@@ -0,0 +1,5 @@
+// swag:group /a alpha
+package groupconflict
+
+// swag:route ping GET /ping
+// Ping
@@ -0,0 +1,2 @@
+// swag:group /b beta
+package groupconflict
@@ -0,0 +1,2 @@
+// swag:group /a alpha
+package groupconflict
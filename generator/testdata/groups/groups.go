@@ -0,0 +1,5 @@
+// swag:group /api/ api
+package groups
+
+// swag:route health GET /health
+// Health check
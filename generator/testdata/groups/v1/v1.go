@@ -0,0 +1,8 @@
+// swag:group /v1 v1
+package v1
+
+// swag:route listUsers GET /users users api
+// List users
+
+// swag:route root GET /
+// The version root
@@ -0,0 +1,109 @@
+package generator
+
+import (
+	"fmt"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/scanner"
+	"go/token"
+	"go/types"
+	"io"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// typeCheck parses and type checks pkgs, as loaded with LoadMode. Their
+// imports come from the export data go list built for them, read by the
+// importer of the toolchain itself: go/packages could only read export data
+// of the toolchains it knew when it was pinned, and would otherwise type
+// check every dependency from source.
+func typeCheck(pkgs []*packages.Package) {
+	fset := token.NewFileSet()
+	var wg sync.WaitGroup
+	for _, pkg := range pkgs {
+		wg.Add(1)
+		go func(pkg *packages.Package) {
+			defer wg.Done()
+			checkPackage(fset, pkg)
+		}(pkg)
+	}
+	wg.Wait()
+}
+
+func checkPackage(fset *token.FileSet, pkg *packages.Package) {
+	errs := []packages.Error{}
+	addError := func(pos token.Position, msg string, kind packages.ErrorKind) {
+		errs = append(errs, packages.Error{Pos: pos.String(), Msg: msg, Kind: kind})
+	}
+
+	pkg.Fset = fset
+	pkg.Syntax = []*ast.File{}
+	files := pkg.CompiledGoFiles
+	if len(files) == 0 {
+		files = pkg.GoFiles
+	}
+	for _, name := range files {
+		file, err := parser.ParseFile(fset, name, nil, parser.ParseComments)
+		if file != nil {
+			pkg.Syntax = append(pkg.Syntax, file)
+		}
+		if list, ok := err.(scanner.ErrorList); ok {
+			for _, e := range list {
+				addError(e.Pos, e.Msg, packages.ParseError)
+			}
+		} else if err != nil {
+			addError(token.Position{Filename: name}, err.Error(), packages.ParseError)
+		}
+	}
+
+	lookup := func(path string) (io.ReadCloser, error) {
+		imp, ok := pkg.Imports[path]
+		if !ok || imp.ExportFile == "" {
+			return nil, fmt.Errorf("no export data for %s", path)
+		}
+		return os.Open(imp.ExportFile)
+	}
+	arch := os.Getenv("GOARCH")
+	if arch == "" {
+		arch = runtime.GOARCH
+	}
+	conf := types.Config{
+		Importer: importer.ForCompiler(fset, "gc", lookup),
+		Sizes:    types.SizesFor("gc", arch),
+		Error: func(err error) {
+			if e, ok := err.(types.Error); ok {
+				addError(e.Fset.Position(e.Pos), e.Msg, packages.TypeError)
+			} else {
+				addError(token.Position{}, err.Error(), packages.TypeError)
+			}
+		},
+	}
+	pkg.TypesInfo = &types.Info{
+		Types:      map[ast.Expr]types.TypeAndValue{},
+		Defs:       map[*ast.Ident]types.Object{},
+		Uses:       map[*ast.Ident]types.Object{},
+		Implicits:  map[ast.Node]types.Object{},
+		Selections: map[*ast.SelectorExpr]*types.Selection{},
+		Scopes:     map[ast.Node]*types.Scope{},
+	}
+	pkg.Types, _ = conf.Check(pkg.PkgPath, fset, pkg.Syntax, pkg.TypesInfo)
+
+	// go list compiled the package for its export data too; when that
+	// failed, the errors above tell the same with proper positions
+	if len(errs) > 0 {
+		kept := []packages.Error{}
+		for _, e := range pkg.Errors {
+			if !strings.HasPrefix(e.Msg, "# "+pkg.PkgPath+"\n") {
+				kept = append(kept, e)
+			}
+		}
+		pkg.Errors = kept
+	}
+	pkg.Errors = append(pkg.Errors, errs...)
+	pkg.IllTyped = len(pkg.Errors) > 0
+}
@@ -0,0 +1,71 @@
+package generator
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/tools/go/packages"
+)
+
+func TestTypeCheck(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go command not found")
+	}
+	dir, err := ioutil.TempDir("", "go2swag-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	write := func(name, src string) {
+		name = filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(name, []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("go.mod", "module example.com/check\n\ngo 1.13\n")
+	write("ok/ok.go", "package ok\n\nimport (\n\t\"net/http\"\n\t\"time\"\n)\n\ntype Event struct {\n\tAt     time.Time\n\tHeader http.Header\n}\n")
+	write("bad/bad.go", "package bad\n\nimport \"time\"\n\ntype Event struct {\n\tAt time.Tme\n}\n")
+
+	pkgs, err := packages.Load(&packages.Config{
+		Dir:  dir,
+		Mode: LoadMode,
+		Env:  append(os.Environ(), "GO111MODULE=on", "GOFLAGS=-mod=mod", "GOPROXY=off"),
+	}, "./ok", "./bad")
+	if err != nil {
+		t.Fatal(err)
+	}
+	typeCheck(pkgs)
+
+	byPath := map[string]*packages.Package{}
+	for _, pkg := range pkgs {
+		byPath[pkg.PkgPath] = pkg
+	}
+
+	ok := byPath["example.com/check/ok"]
+	if len(ok.Errors) > 0 {
+		t.Fatalf("ok: got errors %v", ok.Errors)
+	}
+	if ok.Fset == nil || len(ok.Syntax) != 1 || ok.TypesInfo == nil {
+		t.Fatalf("ok: syntax and types info are not filled in")
+	}
+	want := "struct{At time.Time; Header net/http.Header}"
+	if got := ok.Types.Scope().Lookup("Event").Type().Underlying().String(); got != want {
+		t.Errorf("ok: got %s, want %s", got, want)
+	}
+
+	bad := byPath["example.com/check/bad"]
+	if len(bad.Errors) != 1 || !strings.HasSuffix(bad.Errors[0].Pos, "bad.go:6:10") || bad.Errors[0].Kind != packages.TypeError {
+		t.Errorf("bad: got errors %v, want one type error at bad.go:6:10", bad.Errors)
+	}
+	if !bad.IllTyped {
+		t.Errorf("bad: not marked ill typed")
+	}
+}
@@ -0,0 +1,473 @@
+package generator
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-openapi/spec"
+)
+
+var rxTSIdent = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*$`)
+
+// TypeScript renders the definitions of the spec as TypeScript interfaces
+// and every operation as a typed fetch wrapper. Definitions built from Go
+// types keep the Go type name when the scan is given and the name is unique.
+// Enums become literal unions, omitempty and pointer fields optional ones,
+// and descriptions TSDoc comments.
+func TypeScript(sw *spec.Swagger, scan *Scanner) ([]byte, error) {
+	w := tsWriter{sw: sw, names: map[string]string{}, used: map[string]bool{}}
+	for _, name := range []string{"ClientOptions", "ApiError"} {
+		w.used[name] = true
+	}
+
+	ops := w.operations()
+	for _, op := range ops {
+		w.used[op.Type] = true
+	}
+	w.nameDefinitions(scan)
+
+	w.printf("// Code generated by go2swag. DO NOT EDIT.\n\n")
+
+	defs := []string{}
+	for name := range sw.Definitions {
+		defs = append(defs, name)
+	}
+	sort.Strings(defs)
+	for _, name := range defs {
+		schema := sw.Definitions[name]
+		w.doc("", schema.Description)
+		if len(schema.Properties) > 0 || schema.Type.Contains("object") && schema.AdditionalProperties == nil {
+			w.printf("export interface %s %s\n\n", w.names[name], w.objectType(&schema, ""))
+		} else {
+			w.printf("export type %s = %s;\n\n", w.names[name], w.typeOf(&schema, ""))
+		}
+	}
+
+	w.printf("%s", tsRuntime)
+	w.printf("export const basePath = %s;\n", tsString(strings.TrimSuffix(sw.BasePath, "/")))
+
+	for _, op := range ops {
+		w.operation(op)
+	}
+	return w.buf.Bytes(), nil
+}
+
+type tsWriter struct {
+	sw  *spec.Swagger
+	buf bytes.Buffer
+	// names maps definition names to TypeScript names
+	names map[string]string
+	used  map[string]bool
+}
+
+type tsOperation struct {
+	Func, Type   string
+	Method, Path string
+	Op           *spec.Operation
+}
+
+func (self *tsWriter) printf(format string, args ...interface{}) {
+	fmt.Fprintf(&self.buf, format, args...)
+}
+
+// unique returns name, or name with a number when it is taken.
+func (self *tsWriter) unique(name string) string {
+	if name == "" {
+		name = "Type"
+	}
+	base := name
+	for i := 2; self.used[name]; i++ {
+		name = base + strconv.Itoa(i)
+	}
+	self.used[name] = true
+	return name
+}
+
+func (self *tsWriter) nameDefinitions(scan *Scanner) {
+	goNames := map[string]string{}
+	counts := map[string]int{}
+	if scan != nil {
+		for _, d := range append(scan.Requests(), scan.Responses()...) {
+			if _, ok := self.sw.Definitions[d.Name]; ok {
+				goNames[d.Name] = d.TypeName
+				counts[d.TypeName]++
+			}
+		}
+	}
+
+	defs := []string{}
+	for name := range self.sw.Definitions {
+		defs = append(defs, name)
+	}
+	sort.Strings(defs)
+	for _, name := range defs {
+		if goName, ok := goNames[name]; ok && counts[goName] == 1 && !self.used[goName] {
+			self.names[name] = self.unique(goName)
+		} else {
+			self.names[name] = self.unique(exportedName(name))
+		}
+	}
+}
+
+func (self *tsWriter) operations() []tsOperation {
+	ops := []tsOperation{}
+	if self.sw.Paths == nil {
+		return ops
+	}
+	paths := []string{}
+	for p := range self.sw.Paths.Paths {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	// the runtime's own names
+	funcs := map[string]bool{"request": true, "defaults": true, "basePath": true}
+	for _, p := range paths {
+		item := self.sw.Paths.Paths[p]
+		for _, m := range []struct {
+			name string
+			op   *spec.Operation
+		}{{"GET", item.Get}, {"POST", item.Post}, {"PUT", item.Put}, {"PATCH", item.Patch}, {"DELETE", item.Delete}, {"HEAD", item.Head}, {"OPTIONS", item.Options}} {
+			if m.op == nil {
+				continue
+			}
+			name := exportedName(m.op.ID)
+			if name == "" {
+				name = exportedName(strings.ToLower(m.name) + " " + p)
+			}
+			fn := strings.ToLower(name[:1]) + name[1:]
+			for i := 2; funcs[fn]; i++ {
+				fn = strings.ToLower(name[:1]) + name[1:] + strconv.Itoa(i)
+			}
+			funcs[fn] = true
+			ops = append(ops, tsOperation{Func: fn, Type: name + "Response", Method: m.name, Path: p, Op: m.op})
+		}
+	}
+	return ops
+}
+
+func (self *tsWriter) operation(o tsOperation) {
+	op := o.Op
+
+	var body *spec.Parameter
+	fields := []string{}
+	names := map[string][]string{}
+	optional := true
+	for i := range op.Parameters {
+		p := op.Parameters[i]
+		if p.In == "body" {
+			body = &p
+			continue
+		}
+		if p.In != "path" && p.In != "query" && p.In != "header" {
+			continue
+		}
+		names[p.In] = append(names[p.In], tsString(p.Name))
+		required := p.Required || p.In == "path"
+		optional = optional && !required
+		fields = append(fields, tsProperty(p.Name, !required)+": "+self.paramType(&p))
+	}
+
+	// response type, a union over the declared statuses
+	members := []string{}
+	statuses := "[]"
+	if op.Responses != nil {
+		codes := []int{}
+		for code := range op.Responses.StatusCodeResponses {
+			codes = append(codes, code)
+		}
+		sort.Ints(codes)
+		list := []string{}
+		for _, code := range codes {
+			resp := op.Responses.StatusCodeResponses[code]
+			members = append(members, fmt.Sprintf("{ status: %d; body: %s }", code, self.bodyType(resp.Schema)))
+			list = append(list, strconv.Itoa(code))
+		}
+		statuses = "[" + strings.Join(list, ", ") + "]"
+		if op.Responses.Default != nil {
+			members = append(members, fmt.Sprintf("{ status: number; body: %s }", self.bodyType(op.Responses.Default.Schema)))
+			statuses = "null"
+		}
+	}
+	if len(members) == 0 {
+		members = append(members, "{ status: number; body: unknown }")
+	}
+	self.printf("\nexport type %s = %s;\n\n", o.Type, strings.Join(members, " | "))
+
+	doc := strings.TrimSpace(op.Summary)
+	if op.Description != "" {
+		doc += "\n\n" + strings.TrimSpace(op.Description)
+	}
+	self.doc("", strings.TrimSpace(o.Method+" "+o.Path+"\n\n"+doc))
+
+	args := []string{}
+	params := "{}"
+	if len(fields) > 0 {
+		arg := "params: { " + strings.Join(fields, "; ") + " }"
+		if optional {
+			arg += " = {}"
+		}
+		args = append(args, arg)
+		params = "params"
+	}
+	bodyArg := "undefined"
+	if body != nil {
+		args = append(args, "body: "+self.bodyType(body.Schema))
+		bodyArg = "body"
+	}
+	args = append(args, "options?: ClientOptions")
+
+	self.printf("export async function %s(%s): Promise<%s> {\n", o.Func, strings.Join(args, ", "), o.Type)
+	self.printf("  return (await request(options, %s, %s, {\n", tsString(o.Method), tsString(o.Path))
+	self.printf("    params: %s,\n", params)
+	for _, in := range []string{"path", "query", "header"} {
+		self.printf("    %s: [%s],\n", in, strings.Join(names[in], ", "))
+	}
+	self.printf("    body: %s,\n", bodyArg)
+	self.printf("    statuses: %s,\n", statuses)
+	self.printf("  })) as %s;\n}\n", o.Type)
+}
+
+func (self *tsWriter) bodyType(schema *spec.Schema) string {
+	if schema == nil {
+		return "unknown"
+	}
+	return self.typeOf(schema, "")
+}
+
+func (self *tsWriter) paramType(p *spec.Parameter) string {
+	if len(p.Enum) > 0 {
+		return tsUnion(p.Enum)
+	}
+	switch p.Type {
+	case "array":
+		item := "string"
+		if p.Items != nil {
+			item = tsPrimitive(p.Items.Type)
+		}
+		return item + "[]"
+	case "file":
+		return "Blob"
+	}
+	return tsPrimitive(p.Type)
+}
+
+func (self *tsWriter) typeOf(schema *spec.Schema, indent string) string {
+	t := self.baseType(schema, indent)
+	if nullable, _ := schema.Extensions.GetBool("x-nullable"); nullable {
+		t += " | null"
+	}
+	return t
+}
+
+func (self *tsWriter) baseType(schema *spec.Schema, indent string) string {
+	if schema.Ref.String() != "" {
+		if name, ok := self.names[refName(schema)]; ok {
+			return name
+		}
+		return "unknown"
+	}
+	if len(schema.Enum) > 0 {
+		return tsUnion(schema.Enum)
+	}
+
+	switch {
+	case schema.Type.Contains("array"):
+		if schema.Items == nil || schema.Items.Schema == nil {
+			return "unknown[]"
+		}
+		item := self.typeOf(schema.Items.Schema, indent)
+		if strings.Contains(item, " | ") {
+			item = "(" + item + ")"
+		}
+		return item + "[]"
+	case len(schema.Properties) > 0:
+		return self.objectType(schema, indent)
+	case schema.AdditionalProperties != nil && schema.AdditionalProperties.Schema != nil:
+		return "Record<string, " + self.typeOf(schema.AdditionalProperties.Schema, indent) + ">"
+	case schema.Type.Contains("object"):
+		return "Record<string, unknown>"
+	case len(schema.Type) > 0:
+		if schema.Format == "binary" {
+			return "Blob"
+		}
+		return tsPrimitive(schema.Type[0])
+	}
+	return "unknown"
+}
+
+func (self *tsWriter) objectType(schema *spec.Schema, indent string) string {
+	required := map[string]bool{}
+	for _, name := range schema.Required {
+		required[name] = true
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("{\n")
+	inner := indent + "  "
+	for _, name := range propertyNames(schema) {
+		prop := schema.Properties[name]
+		omitempty, _ := prop.Extensions.GetBool("x-omitempty")
+		nullable, _ := prop.Extensions.GetBool("x-nullable")
+		buf.WriteString(tsDoc(inner, prop.Description))
+		fmt.Fprintf(&buf, "%s%s: %s;\n", inner, tsProperty(name, !required[name] && (omitempty || nullable)), self.typeOf(&prop, inner))
+	}
+	if schema.AdditionalProperties != nil && schema.AdditionalProperties.Schema != nil {
+		fmt.Fprintf(&buf, "%s[key: string]: %s;\n", inner, self.typeOf(schema.AdditionalProperties.Schema, inner))
+	}
+	buf.WriteString(indent + "}")
+	return buf.String()
+}
+
+func (self *tsWriter) doc(indent, text string) {
+	self.buf.WriteString(tsDoc(indent, text))
+}
+
+// tsDoc is a TSDoc comment of text, empty without text.
+func tsDoc(indent, text string) string {
+	text = strings.TrimSpace(strings.Replace(text, "*/", "*\\/", -1))
+	if text == "" {
+		return ""
+	}
+	lines := strings.Split(text, "\n")
+	if len(lines) == 1 {
+		return indent + "/** " + text + " */\n"
+	}
+	var buf bytes.Buffer
+	buf.WriteString(indent + "/**\n")
+	for _, line := range lines {
+		buf.WriteString(strings.TrimRight(indent+" * "+line, " ") + "\n")
+	}
+	buf.WriteString(indent + " */\n")
+	return buf.String()
+}
+
+func tsProperty(name string, optional bool) string {
+	if !rxTSIdent.MatchString(name) {
+		name = tsString(name)
+	}
+	if optional {
+		name += "?"
+	}
+	return name
+}
+
+func tsPrimitive(t string) string {
+	switch t {
+	case "integer", "number":
+		return "number"
+	case "boolean":
+		return "boolean"
+	case "string":
+		return "string"
+	case "object":
+		return "Record<string, unknown>"
+	}
+	return "unknown"
+}
+
+func tsUnion(values []interface{}) string {
+	list := []string{}
+	for _, v := range values {
+		b, err := json.Marshal(v)
+		if err != nil {
+			continue
+		}
+		list = append(list, string(b))
+	}
+	if len(list) == 0 {
+		return "unknown"
+	}
+	return strings.Join(list, " | ")
+}
+
+func tsString(s string) string {
+	b, _ := json.Marshal(s)
+	return string(b)
+}
+
+// tsRuntime is what the operation wrappers share.
+const tsRuntime = `export interface ClientOptions {
+  /** Scheme and host of the API, e.g. http://localhost:8080; empty for the origin of the page. */
+  baseUrl?: string;
+  /** Sent with every request, e.g. for authentication. */
+  headers?: Record<string, string>;
+  fetch?: typeof fetch;
+}
+
+/** Options of calls that pass none. */
+export const defaults: ClientOptions = { baseUrl: "" };
+
+/** Thrown for a response whose status the operation does not declare. */
+export class ApiError extends Error {
+  constructor(public readonly status: number, public readonly body: unknown) {
+    super("unexpected response " + status);
+  }
+}
+
+interface Call {
+  params: { [name: string]: unknown };
+  path: string[];
+  query: string[];
+  header: string[];
+  body: unknown;
+  // null accepts any status, empty any 2xx
+  statuses: number[] | null;
+}
+
+async function request(options: ClientOptions | undefined, method: string, path: string, call: Call): Promise<{ status: number; body: unknown }> {
+  const opts = { ...defaults, ...options };
+  const value = (name: string) => call.params[name];
+
+  path = path.split("/").map((seg) => {
+    for (const name of call.path) {
+      const v = encodeURIComponent(String(value(name)));
+      if (seg === ":" + name) return v;
+      seg = seg.split("{" + name + "}").join(v);
+    }
+    return seg;
+  }).join("/");
+
+  const query = new URLSearchParams();
+  for (const name of call.query) {
+    const v = value(name);
+    if (v === undefined || v === null) continue;
+    if (Array.isArray(v)) v.forEach((item) => query.append(name, String(item)));
+    else query.set(name, String(v));
+  }
+
+  const headers: Record<string, string> = { Accept: "application/json", ...opts.headers };
+  for (const name of call.header) {
+    const v = value(name);
+    if (v !== undefined && v !== null) headers[name] = String(v);
+  }
+  let body: string | undefined;
+  if (call.body !== undefined) {
+    body = JSON.stringify(call.body);
+    headers["Content-Type"] = "application/json";
+  }
+
+  const qs = query.toString();
+  const send = opts.fetch || fetch;
+  const res = await send((opts.baseUrl || "") + basePath + path + (qs ? "?" + qs : ""), { method, headers, body });
+  const text = await res.text();
+  let data: unknown = undefined;
+  if (text) {
+    try {
+      data = JSON.parse(text);
+    } catch (e) {
+      data = text;
+    }
+  }
+
+  const declared = call.statuses === null || (call.statuses.length ? call.statuses.indexOf(res.status) >= 0 : res.ok);
+  if (!declared) throw new ApiError(res.status, data);
+  return { status: res.status, body: data };
+}
+
+`
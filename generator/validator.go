@@ -0,0 +1,594 @@
+package generator
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/go-openapi/spec"
+)
+
+// GoValidator renders Go code of package pkg with a function per swag:req
+// type checking the required fields, enums, bounds, lengths, patterns and
+// formats its schema declares, and a net/http middleware binding requests
+// to the type of their operation to validate them.
+func GoValidator(sw *spec.Swagger, scan *Scanner, pkg string, diags *Diagnostics) ([]byte, error) {
+	g := &validatorGen{
+		imports: newGoImports("bytes", "errors", "json", "ioutil", "http", "mail", "net", "regexp", "strconv", "strings", "time", "url", "utf8",
+			"operation", "operations", "find", "absent", "errorList", "invalid", "readBody", "writeError", "validatorSplitPath", "validatorMatchPath", "validatorMatchSegment",
+			"isDate", "isDateTime", "isEmail", "isHostname", "isIPv4", "isIPv6", "isURI", "isUUID", "hostnamePattern", "uuidPattern",
+			"r", "w", "s", "v", "x", "op", "req", "errs", "err", "params"),
+		std:      map[string]bool{"bytes": true, "encoding/json": true, "errors": true, "io/ioutil": true, "net/http": true, "strings": true},
+		patterns: map[string]string{},
+		formats:  map[string]bool{},
+	}
+	routes := goRoutes(scan, g.imports, diags)
+
+	names := map[string]bool{"ValidateRequest": true}
+	validators := []validatorFunc{}
+	table := []validatorRoute{}
+	for _, r := range routes {
+		vr := validatorRoute{goRoute: r}
+		if decl, ok := scan.reqs[r.ID]; ok && r.Request != "" {
+			name := "Validate" + decl.TypeName
+			if names[name] {
+				base := "Validate" + exportedName(strings.SplitN(r.Request, ".", 2)[0]) + decl.TypeName
+				name = base
+				for i := 2; names[name]; i++ {
+					name = base + strconv.Itoa(i)
+				}
+			}
+			names[name] = true
+
+			var rules []fieldRule
+			if decl.frag != nil {
+				rules = decl.frag.Rules
+			}
+			params := map[string]bool{}
+			for _, f := range append(append([]goField{}, vr.Query...), vr.Header...) {
+				params[f.Name] = true
+			}
+			var code strings.Builder
+			for _, rule := range rules {
+				g.value(&code, rule, "req."+rule.Field, errPath{lit: rule.Name}, params[rule.Name])
+			}
+			check := "check" + strings.TrimPrefix(name, "Validate")
+			validators = append(validators, validatorFunc{Name: name, Check: check, Type: r.Request, Code: strings.TrimSpace(code.String())})
+			vr.Validator = check
+
+			for _, fields := range [][]goField{vr.PathFields, vr.Query, vr.Header} {
+				for j := range fields {
+					f := &fields[j]
+					bind, ok := g.imports.bindCode(f, fmt.Sprintf("return invalid(%q, err)", f.Name))
+					if !ok {
+						diags.Warnf(r.Pos, CodeCodegen, "route %s: parameter %s cannot be parsed into %s, it is not validated", r.Name, f.Name, f.Field)
+					}
+					f.Bind = strings.TrimSpace(bind)
+					if strings.Contains(f.Bind, "strconv.") {
+						g.std["strconv"] = true
+					}
+				}
+			}
+		}
+		table = append(table, vr)
+	}
+	sort.SliceStable(table, func(i, j int) bool { return morePrecise(table[i].Path, table[j].Path) })
+
+	formats := []string{}
+	for name := range g.formats {
+		formats = append(formats, formatChecks[name].code)
+		for _, path := range formatChecks[name].std {
+			g.std[path] = true
+		}
+	}
+	sort.Strings(formats)
+	if len(g.patternList) > 0 {
+		g.std["regexp"] = true
+	}
+	std := []string{}
+	for path := range g.std {
+		std = append(std, path)
+	}
+	sort.Strings(std)
+
+	var buf bytes.Buffer
+	err := goValidatorTemplate.Execute(&buf, map[string]interface{}{
+		"Package":     pkg,
+		"BasePath":    strings.TrimSuffix(sw.BasePath, "/"),
+		"PathHelpers": goPathHelpers("validator"),
+		"Std":         std,
+		"Imports":     g.imports.list(),
+		"Validators":  validators,
+		"Table":       table,
+		"Patterns":    g.patternList,
+		"Formats":     formats,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return format.Source(buf.Bytes())
+}
+
+type validatorFunc struct {
+	Name, Check, Type string
+	// Code checks req, appending to errs.
+	Code string
+}
+
+type validatorRoute struct {
+	goRoute
+	// Validator is the function checking the request type given the absent
+	// parameters, if any.
+	Validator string
+}
+
+type validatorPattern struct {
+	Name, Pattern string
+}
+
+// validatorGen writes the code checking fields and records what it needs.
+type validatorGen struct {
+	imports *goImports
+	// std are the paths of the standard packages the code uses.
+	std         map[string]bool
+	patterns    map[string]string
+	patternList []validatorPattern
+	formats     map[string]bool
+	vars        int
+}
+
+// errPath is the name of a value in errors, an expression followed by a
+// literal so that adjacent literals are joined.
+type errPath struct {
+	expr, lit string
+}
+
+func (self errPath) String() string {
+	switch {
+	case self.expr == "":
+		return strconv.Quote(self.lit)
+	case self.lit == "":
+		return self.expr
+	}
+	return self.expr + " + " + strconv.Quote(self.lit)
+}
+
+func (self errPath) field(name string) errPath {
+	if self.expr == "" && self.lit == "" {
+		return errPath{lit: name}
+	}
+	return errPath{expr: self.expr, lit: self.lit + "." + name}
+}
+
+func (self errPath) index(i string) errPath {
+	return errPath{expr: errPath{expr: self.expr, lit: self.lit + "["}.String() + " + strconv.Itoa(" + i + ")", lit: "]"}
+}
+
+// rules is the code checking the fields of the struct value.
+func (self *validatorGen) rules(rules []fieldRule, value string, path errPath) string {
+	var b strings.Builder
+	for _, r := range rules {
+		self.value(&b, r, value+"."+r.Field, path.field(r.Name), false)
+	}
+	return b.String()
+}
+
+// value writes the code checking x. A nil pointer, slice or map stands for
+// a missing value, which only fails a required field, as does the zero
+// value of a field left out of JSON when empty and, for a parameter of the
+// request, one listed in absent. Other values are always checked, and a
+// zero one fails a required field.
+func (self *validatorGen) value(b *strings.Builder, r fieldRule, x string, path errPath, param bool) {
+	t := r.Type
+	nilable := t.Pointer || r.Kind == "slice" || r.Kind == "map"
+	inner := x
+	if t.Pointer && r.Kind != "struct" {
+		inner = "*" + x
+	}
+	checks := self.checks(r, inner, path)
+
+	zero := ""
+	switch {
+	case r.Kind != "" || t.Text:
+	case t.Basic == "string":
+		zero = `""`
+	case t.Basic != "" && t.Basic != "bool":
+		zero = "0"
+	}
+
+	// missing fails a required field, present guards the checks
+	missing, present := "", []string{}
+	switch {
+	case nilable:
+		missing = x + " == nil"
+		present = append(present, x+" != nil")
+	case zero != "":
+		missing = x + " == " + zero
+		if r.OmitEmpty {
+			present = append(present, x+" != "+zero)
+		}
+	}
+	if param && !nilable {
+		present = append(present, fmt.Sprintf("!absent[%q]", r.Name))
+	}
+
+	switch {
+	case missing != "" && r.Required:
+		fmt.Fprintf(b, "if %s {\nerrs.add(%s, \"is required\")\n}", missing, path)
+		if checks != "" {
+			fmt.Fprintf(b, " else {\n%s}", checks)
+		}
+		b.WriteString("\n")
+	case checks == "":
+	case len(present) > 0:
+		fmt.Fprintf(b, "if %s {\n%s}\n", strings.Join(present, " && "), checks)
+	default:
+		b.WriteString(checks)
+	}
+}
+
+// checks is the code checking the constraints of a present value v.
+func (self *validatorGen) checks(r fieldRule, v string, path errPath) string {
+	var b strings.Builder
+	add := func(cond, message string) {
+		fmt.Fprintf(&b, "if %s {\nerrs.add(%s, %q)\n}\n", cond, path, message)
+	}
+	length := func(n *int64, op, message string) {
+		if n != nil {
+			self.std["unicode/utf8"] = true
+			add(fmt.Sprintf("utf8.RuneCountInString(string(%s)) %s %d", v, op, *n), fmt.Sprintf(message, *n))
+		}
+	}
+	bound := func(n *float64, exclusive bool, op, message, exclusiveMessage string) {
+		if n == nil {
+			return
+		}
+		if exclusive {
+			op, message = op+"=", exclusiveMessage
+		}
+		add(fmt.Sprintf("float64(%s) %s %s", v, op, formatNumber(*n)), message+" "+formatNumber(*n))
+	}
+	items := func(n *int64, op, message string) {
+		if n != nil {
+			add(fmt.Sprintf("len(%s) %s %d", v, op, *n), fmt.Sprintf(message, *n))
+		}
+	}
+
+	switch {
+	case r.Type.Basic == "string":
+		length(r.MinLength, "<", "must be at least %d characters long")
+		length(r.MaxLength, ">", "must be at most %d characters long")
+		if r.Pattern != "" {
+			add(fmt.Sprintf("!%s.MatchString(string(%s))", self.pattern(r.Pattern), v), "must match "+r.Pattern)
+		}
+		if check, ok := formatChecks[r.Format]; ok {
+			self.formats[r.Format] = true
+			add(fmt.Sprintf("!%s(string(%s))", check.fn, v), check.message)
+		}
+	case r.Type.Basic != "" && r.Type.Basic != "bool":
+		bound(r.Minimum, r.ExclusiveMinimum, "<", "must be at least", "must be greater than")
+		bound(r.Maximum, r.ExclusiveMaximum, ">", "must be at most", "must be less than")
+	case r.Kind == "slice" || r.Kind == "array":
+		items(r.MinItems, "<", "must have at least %d items")
+		items(r.MaxItems, ">", "must have at most %d items")
+		if r.Elem != nil {
+			i, e := fmt.Sprintf("i%d", self.vars), fmt.Sprintf("e%d", self.vars)
+			self.vars++
+			var eb strings.Builder
+			self.value(&eb, *r.Elem, e, path.index(i), false)
+			if eb.Len() > 0 {
+				self.std["strconv"] = true
+				fmt.Fprintf(&b, "for %s, %s := range %s {\n%s}\n", i, e, v, eb.String())
+			}
+		}
+	case r.Kind == "struct":
+		b.WriteString(self.rules(r.Fields, v, path))
+	}
+
+	if len(r.Enum) > 0 && r.Type.Basic != "" && !r.Type.Text {
+		cases, names := []string{}, []string{}
+		seen := map[string]bool{}
+		for _, value := range r.Enum {
+			lit, ok := enumLiteral(value, r.Type.Basic)
+			if !ok || seen[lit] {
+				continue
+			}
+			seen[lit] = true
+			cases = append(cases, lit)
+			names = append(names, fmt.Sprint(value))
+		}
+		if len(cases) > 0 {
+			fmt.Fprintf(&b, "switch %s {\ncase %s:\ndefault:\nerrs.add(%s, %q)\n}\n", v, strings.Join(cases, ", "), path, "must be one of "+strings.Join(names, ", "))
+		}
+	}
+	return b.String()
+}
+
+// pattern is the variable holding the compiled pattern.
+func (self *validatorGen) pattern(pattern string) string {
+	name, ok := self.patterns[pattern]
+	if !ok {
+		name = "pattern" + strconv.Itoa(len(self.patternList))
+		self.patterns[pattern] = name
+		self.patternList = append(self.patternList, validatorPattern{Name: name, Pattern: pattern})
+	}
+	return name
+}
+
+// enumLiteral is an enum value as a constant of the basic type, false if
+// it is not one.
+func enumLiteral(value interface{}, basic string) (string, bool) {
+	switch basic {
+	case "string":
+		s, ok := value.(string)
+		return strconv.Quote(s), ok
+	case "bool":
+		b, ok := value.(bool)
+		return strconv.FormatBool(b), ok
+	}
+
+	var f float64
+	switch n := value.(type) {
+	case int64:
+		f = float64(n)
+	case float64:
+		f = n
+	default:
+		return "", false
+	}
+	if !strings.HasPrefix(basic, "float") && f != math.Trunc(f) || strings.HasPrefix(basic, "uint") && f < 0 {
+		return "", false
+	}
+	return formatNumber(f), true
+}
+
+func formatNumber(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// formatChecks are the string formats generated validation code checks,
+// with the function doing it.
+var formatChecks = map[string]struct {
+	fn, message, code string
+	std               []string
+}{
+	"date": {"isDate", "must be a date like 2006-01-02", `func isDate(s string) bool {
+	_, err := time.Parse("2006-01-02", s)
+	return err == nil
+}`, []string{"time"}},
+	"date-time": {"isDateTime", "must be an RFC 3339 date and time", `func isDateTime(s string) bool {
+	_, err := time.Parse(time.RFC3339, s)
+	return err == nil
+}`, []string{"time"}},
+	"email": {"isEmail", "must be an email address", `func isEmail(s string) bool {
+	a, err := mail.ParseAddress(s)
+	return err == nil && a.Address == s
+}`, []string{"net/mail"}},
+	"hostname": {"isHostname", "must be a host name", `var hostnamePattern = regexp.MustCompile(` + "`" + `^[a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?(\.[a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)*$` + "`" + `)
+
+func isHostname(s string) bool {
+	return len(s) <= 253 && hostnamePattern.MatchString(s)
+}`, []string{"regexp"}},
+	"ipv4": {"isIPv4", "must be an IPv4 address", `func isIPv4(s string) bool {
+	return net.ParseIP(s) != nil && !strings.Contains(s, ":")
+}`, []string{"net"}},
+	"ipv6": {"isIPv6", "must be an IPv6 address", `func isIPv6(s string) bool {
+	return net.ParseIP(s) != nil && strings.Contains(s, ":")
+}`, []string{"net"}},
+	"uri": {"isURI", "must be an absolute URI", `func isURI(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && u.IsAbs()
+}`, []string{"net/url"}},
+	"uuid": {"isUUID", "must be a UUID", `var uuidPattern = regexp.MustCompile(` + "`" + `^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$` + "`" + `)
+
+func isUUID(s string) bool {
+	return uuidPattern.MatchString(s)
+}`, []string{"regexp"}},
+}
+
+// checkedFormat is format if validation code checks it, empty otherwise.
+func checkedFormat(format string) string {
+	if _, ok := formatChecks[format]; ok {
+		return format
+	}
+	return ""
+}
+
+var goValidatorTemplate = template.Must(template.New("").Parse(`// Code generated by go2swag. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+{{- range .Std}}
+	{{printf "%q" .}}{{end}}
+{{range .Imports}}
+	{{.Alias}} {{printf "%q" .Path}}{{end}}
+)
+
+
+// FieldError is what is wrong with a field, query parameter or header of a
+// request.
+type FieldError struct {
+	Field   string ` + "`json:\"field\"`" + `
+	Message string ` + "`json:\"message\"`" + `
+}
+
+// ValidationError lists what is wrong with a request. Middleware sends it as
+// the JSON body of a 400 Bad Request response.
+type ValidationError struct {
+	// Operation is the operation id of the request, if known.
+	Operation string       ` + "`json:\"operation,omitempty\"`" + `
+	Errors    []FieldError ` + "`json:\"errors\"`" + `
+}
+
+func (e *ValidationError) Error() string {
+	msgs := []string{}
+	for _, fe := range e.Errors {
+		msgs = append(msgs, fe.Field+" "+fe.Message)
+	}
+	return "invalid request: " + strings.Join(msgs, "; ")
+}
+{{range .Validators}}
+// {{.Name}} checks the constraints of the spec on {{.Type}}; the error
+// is a *ValidationError listing every violation.
+func {{.Name}}(req *{{.Type}}) error {
+	return {{.Check}}(req, nil)
+}
+
+// {{.Check}} is {{.Name}} for a request whose parameters in absent were
+// not sent.
+func {{.Check}}(req *{{.Type}}, absent map[string]bool) error {
+	if req == nil {
+		return nil
+	}
+{{- if .Code}}
+	var errs errorList
+	{{.Code}}
+	return errs.err()
+{{- else}}
+	return nil
+{{- end}}
+}
+{{end}}
+// Middleware validates requests of the operations of the spec before next
+// serves them, binding path, query, header and body parameters like the
+// generated server does. Invalid requests are answered 400 Bad Request with
+// a JSON ValidationError, requests of no operation are passed on.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if op, params := find(r); op != nil && op.validate != nil {
+			if err := op.validate(r, params); err != nil {
+				writeError(w, op.id, err)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// ValidateRequest validates r as a request of the operation with the given
+// id; the error is a *ValidationError when r is invalid. The body of r can
+// be read again afterwards.
+func ValidateRequest(operationID string, r *http.Request) error {
+	for _, op := range operations {
+		if op.id != operationID {
+			continue
+		}
+		if op.validate == nil {
+			return nil
+		}
+		params, _ := validatorMatchPath(op.path, validatorSplitPath(r.URL.Path))
+		return op.validate(r, params)
+	}
+	return errors.New("unknown operation " + operationID)
+}
+
+type operation struct {
+	id, method string
+	path       []string
+	validate   func(r *http.Request, params map[string]string) error
+}
+
+// operations are ordered with literal path segments before parameters.
+var operations = []operation{
+{{- range .Table}}
+	{ {{- printf "%q" .ID}}, {{printf "%q" .Method}}, validatorSplitPath({{printf "%q" (print $.BasePath .Path)}}), {{if .Validator}}validate{{.Name}}{{else}}nil{{end}}},{{end}}
+}
+{{range .Table}}{{if .Validator}}
+func validate{{.Name}}(r *http.Request, params map[string]string) error {
+	req := new({{.Request}})
+	absent := map[string]bool{}
+{{- if .Body}}
+	if err := readBody(r, req); err != nil {
+		return invalid("body", err)
+	}{{end}}
+{{- range .PathFields}}{{if .Bind}}
+	if s := params[{{printf "%q" .Name}}]; s != "" {
+		{{.Bind}}
+	}{{end}}{{end}}
+{{- range .Query}}{{if .Bind}}
+	if s := r.URL.Query().Get({{printf "%q" .Name}}); s != "" {
+		{{.Bind}}
+	} else {
+		absent[{{printf "%q" .Name}}] = true
+	}{{else}}
+	absent[{{printf "%q" .Name}}] = true{{end}}{{end}}
+{{- range .Header}}{{if .Bind}}
+	if s := r.Header.Get({{printf "%q" .Name}}); s != "" {
+		{{.Bind}}
+	} else {
+		absent[{{printf "%q" .Name}}] = true
+	}{{else}}
+	absent[{{printf "%q" .Name}}] = true{{end}}{{end}}
+	return {{.Validator}}(req, absent)
+}
+{{end}}{{end}}
+type errorList []FieldError
+
+func (l *errorList) add(field, message string) {
+	*l = append(*l, FieldError{Field: field, Message: message})
+}
+
+func (l errorList) err() error {
+	if len(l) == 0 {
+		return nil
+	}
+	return &ValidationError{Errors: l}
+}
+
+// invalid is the error of a parameter or body that cannot be parsed.
+func invalid(field string, err error) error {
+	return &ValidationError{Errors: []FieldError{ {Field: field, Message: "cannot be parsed: " + err.Error()} }}
+}
+
+func writeError(w http.ResponseWriter, operation string, err error) {
+	verr, ok := err.(*ValidationError)
+	if !ok {
+		verr = &ValidationError{Errors: []FieldError{ {Field: "body", Message: err.Error()} }}
+	}
+	verr.Operation = operation
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	json.NewEncoder(w).Encode(verr)
+}
+
+// readBody decodes a JSON body into v and leaves it to be read again.
+func readBody(r *http.Request, v interface{}) error {
+	if r.Body == nil {
+		return nil
+	}
+	b, err := ioutil.ReadAll(r.Body)
+	r.Body = ioutil.NopCloser(bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	if len(bytes.TrimSpace(b)) == 0 {
+		return nil
+	}
+	return json.Unmarshal(b, v)
+}
+
+// find is the operation of the request and its path parameters.
+func find(r *http.Request) (*operation, map[string]string) {
+	path := validatorSplitPath(r.URL.Path)
+	for i := range operations {
+		if operations[i].method != r.Method {
+			continue
+		}
+		if params, ok := validatorMatchPath(operations[i].path, path); ok {
+			return &operations[i], params
+		}
+	}
+	return nil, nil
+}
+{{range .Patterns}}
+var {{.Name}} = regexp.MustCompile({{printf "%q" .Pattern}})
+{{end}}{{range .Formats}}
+{{.}}
+{{end}}
+{{.PathHelpers}}`))
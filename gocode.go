@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"go/token"
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/go-openapi/spec"
+	"github.com/xucx/go2swag/generator"
+)
+
+type goCodeOptions struct {
+	Out     string `goptions:"-o, description='Go output file (default: stdout)'"`
+	Package string `goptions:"--package, description='package of the Go file (default: the package in the output directory)'"`
+}
+
+type contractOptions struct {
+	Out     string `goptions:"-o, description='Go test file (default: stdout)'"`
+	Package string `goptions:"--package, description='package of the test file (default: the package in the output directory)'"`
+	Handler string `goptions:"--handler, description='function of the package returning the handler under test (default: newContractHandler)'"`
+}
+
+// client writes a Go client calling the scanned routes with their own
+// request and response types.
+func client(opt options, diags *generator.Diagnostics) int {
+	return goCode(opt, opt.Client, "client", generator.GoClient, diags)
+}
+
+// server writes interfaces for the handlers of the scanned routes and the
+// http.Handler binding requests to them.
+func server(opt options, diags *generator.Diagnostics) int {
+	return goCode(opt, opt.Server, "server", generator.GoServer, diags)
+}
+
+// validator writes functions checking the constraints of the request types
+// and a middleware validating requests before they are served.
+func validator(opt options, diags *generator.Diagnostics) int {
+	return goCode(opt, opt.Valid, "validator", generator.GoValidator, diags)
+}
+
+// contract writes a test serving a request per operation with the handler
+// of the package and checking its responses against the spec.
+func contract(opt options, diags *generator.Diagnostics) int {
+	handler := opt.Contract.Handler
+	if handler == "" {
+		handler = "newContractHandler"
+	}
+	code := goCodeOptions{Out: opt.Contract.Out, Package: opt.Contract.Package}
+	return goCode(opt, code, "main", func(swag *spec.Swagger, _ *generator.Scanner, pkg string, diags *generator.Diagnostics) ([]byte, error) {
+		return generator.GoContract(swag, pkg, handler, diags)
+	}, diags)
+}
+
+func goCode(opt options, code goCodeOptions, defaultPkg string, render func(*spec.Swagger, *generator.Scanner, string, *generator.Diagnostics) ([]byte, error), diags *generator.Diagnostics) int {
+	swag, scan := generateScan(opt, diags)
+	if swag == nil {
+		return 1
+	}
+
+	pkg := code.Package
+	if pkg == "" {
+		pkg = defaultPkg
+		if code.Out != "" {
+			pkg = generator.PackageName(filepath.Dir(code.Out))
+		}
+	}
+
+	b, err := render(swag, scan, pkg, diags)
+	if err != nil {
+		diags.Errorf(token.Position{}, generator.CodeOutput, "%v", err)
+		return 1
+	}
+
+	if code.Out == "" {
+		fmt.Print(string(b))
+		return 0
+	}
+	if err := ioutil.WriteFile(code.Out, b, 0644); err != nil {
+		diags.Errorf(token.Position{Filename: code.Out}, generator.CodeOutput, "%v", err)
+		return 1
+	}
+	return 0
+}
@@ -1,23 +1,47 @@
 package main
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
-	"io/ioutil"
+	"go/token"
 	"os"
-	"strings"
+	"path/filepath"
+	"time"
 
-	"github.com/go-openapi/loads"
 	"github.com/go-openapi/spec"
 	"github.com/voxelbrain/goptions"
-	"golang.org/x/tools/go/packages"
-	"gopkg.in/yaml.v2"
+	"github.com/xucx/go2swag/generator"
 )
 
 type options struct {
-	In     string   `goptions:"-i, description='in'"`
-	Out    string   `goptions:"-o, description='out'"`
-	Models []string `goptions:"-m, description='models'"`
+	In          []string      `goptions:"-i, description='in, may be repeated to merge several specs'"`
+	OnConflict  string        `goptions:"--on-conflict, description='how to settle disagreeing inputs: error, first or last'"`
+	Overlays    []string      `goptions:"--overlay, description='overlay document applied after generation, may be repeated'"`
+	Plugins     []string      `goptions:"--plugin, description='external command post-processing the spec, may be repeated'"`
+	Out         string        `goptions:"-o, description='out'"`
+	Models      []string      `goptions:"-m, description='models'"`
+	Include     []string      `goptions:"--include, description='package pattern scanned for annotations, may be repeated (default: main module)'"`
+	Exclude     []string      `goptions:"--exclude, description='package pattern not scanned for annotations, may be repeated'"`
+	Jobs        int           `goptions:"--jobs, description='files scanned in parallel (default: number of CPUs)'"`
+	NoCache     bool          `goptions:"--no-cache, description='neither read nor write the scan cache'"`
+	ClearCache  bool          `goptions:"--clear-cache, description='empty the scan cache before running'"`
+	Diagnostics string        `goptions:"--diagnostics, description='diagnostics format: text, json or sarif'"`
+	Strict      bool          `goptions:"--strict, description='exit non-zero on warnings too'"`
+	Interval    time.Duration `goptions:"--interval, description='watch, serve and mock: how often sources are polled (default: 500ms)'"`
+	Debounce    time.Duration `goptions:"--debounce, description='watch, serve and mock: quiet time after a change before regenerating (default: 300ms)'"`
+
+	Verb     goptions.Verbs
+	Check    struct{}        `goptions:"check"`
+	Client   goCodeOptions   `goptions:"client"`
+	Contract contractOptions `goptions:"contract"`
+	Diff     diffOptions     `goptions:"diff"`
+	Mock     mockOptions     `goptions:"mock"`
+	Model    modelOptions    `goptions:"model"`
+	Watch    struct{}        `goptions:"watch"`
+	Serve    serveOptions    `goptions:"serve"`
+	Server   goCodeOptions   `goptions:"server"`
+	TS       tsOptions       `goptions:"typescript"`
+	Valid    goCodeOptions   `goptions:"validator"`
 }
 
 func main() {
@@ -28,73 +52,110 @@ func main() {
 	}
 	goptions.ParseAndFail(&opt)
 
-	pkgs, err := packages.Load(&packages.Config{
-		Dir:   ".",
-		Mode:  pkgLoadMode,
-		Tests: false,
-	}, opt.Models...)
-	if err != nil {
-		fmt.Println(err)
-		return
-	}
+	diags := new(generator.Diagnostics)
+	code := 0
 
-	scanner, err := scan(pkgs)
-	if err != nil {
-		fmt.Println(err)
-		return
-	}
-
-	swag, err := build(scanner, load(opt.In))
-	if err != nil {
-		fmt.Println(err)
-		return
+	if opt.ClearCache {
+		if dir := cacheDir(opt); dir != "" {
+			if err := os.RemoveAll(dir); err != nil {
+				diags.Errorf(token.Position{Filename: dir}, generator.CodeOutput, "%v", err)
+			}
+		}
 	}
 
-	save(swag, true, opt.Out)
-}
-
-func load(input string) *spec.Swagger {
-	if fi, err := os.Stat(input); err == nil {
-		if !fi.IsDir() {
-			if sp, err := loads.Spec(input); err == nil {
-				return sp.Spec()
+	switch opt.Verb {
+	case "check":
+		code = check(opt, diags)
+	case "client":
+		code = client(opt, diags)
+	case "contract":
+		code = contract(opt, diags)
+	case "diff":
+		code = diff(opt, diags)
+	case "mock":
+		code = mock(opt, diags)
+	case "model":
+		code = model(opt, diags)
+	case "watch":
+		code = watch(opt, diags)
+	case "serve":
+		code = serve(opt, diags)
+	case "server":
+		code = server(opt, diags)
+	case "typescript":
+		code = typescript(opt, diags)
+	case "validator":
+		code = validator(opt, diags)
+	default:
+		if swag := generate(opt, diags); swag != nil {
+			if err := generator.Save(swag, true, opt.Out); err != nil {
+				diags.Errorf(token.Position{Filename: opt.Out}, generator.CodeOutput, "%v", err)
 			}
 		}
 	}
-	return nil
-}
 
-func save(swspec *spec.Swagger, pretty bool, output string) error {
-	var b []byte
-	var err error
+	if err := diags.Render(os.Stderr, opt.Diagnostics); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if diags.Failed(opt.Strict) {
+		os.Exit(1)
+	}
+	os.Exit(code)
+}
 
-	if strings.HasSuffix(output, "yml") || strings.HasSuffix(output, "yaml") {
-		b, err = json.Marshal(swspec)
-		if err != nil {
-			return err
-		}
+func generate(opt options, diags *generator.Diagnostics) *spec.Swagger {
+	return generateIn(".", opt, diags)
+}
 
-		var jsonObj interface{}
-		if err := yaml.Unmarshal(b, &jsonObj); err != nil {
-			return err
-		}
+func generateIn(dir string, opt options, diags *generator.Diagnostics) *spec.Swagger {
+	return generateWith(config(dir, opt), diags)
+}
 
-		b, err = yaml.Marshal(jsonObj)
-	} else {
-		if pretty {
-			b, err = json.MarshalIndent(swspec, "", "  ")
-		}
-		b, err = json.Marshal(swspec)
+func config(dir string, opt options) generator.Config {
+	return generator.Config{
+		Dir:        dir,
+		Patterns:   opt.Models,
+		Include:    opt.Include,
+		Exclude:    opt.Exclude,
+		Inputs:     opt.In,
+		OnConflict: opt.OnConflict,
+		Overlays:   opt.Overlays,
+		Plugins:    opt.Plugins,
+		Jobs:       opt.Jobs,
+		CacheDir:   cacheDir(opt),
 	}
+}
 
+func generateWith(cfg generator.Config, diags *generator.Diagnostics) *spec.Swagger {
+	swag, d, err := generator.Generate(context.Background(), cfg)
+	diags.Append(d.List()...)
 	if err != nil {
-		return err
+		diags.Errorf(token.Position{}, generator.CodeLoad, "%v", err)
+		return nil
 	}
+	return swag
+}
 
-	if output == "" {
-		fmt.Println(string(b))
+// generateScan is generate that also returns the scan the spec was built from.
+func generateScan(opt options, diags *generator.Diagnostics) (*spec.Swagger, *generator.Scanner) {
+	var scan *generator.Scanner
+	cfg := config(".", opt)
+	cfg.PostProcessors = append(cfg.PostProcessors, func(sw *spec.Swagger, s *generator.Scanner) error {
+		scan = s
 		return nil
-	}
+	})
+	return generateWith(cfg, diags), scan
+}
 
-	return ioutil.WriteFile(output, b, 0644)
+// cacheDir is where scans are cached, under the user cache directory.
+func cacheDir(opt options) string {
+	if opt.NoCache {
+		return ""
+	}
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "go2swag")
 }
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"go/token"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/go-openapi/spec"
+	"github.com/xucx/go2swag/generator"
+)
+
+type mockOptions struct {
+	Addr  string `goptions:"--addr, description='address to listen on (default: localhost:8080)'"`
+	Quiet bool   `goptions:"--quiet, description='do not log requests'"`
+}
+
+// mockServer answers with the mock of the latest generated spec.
+type mockServer struct {
+	mu      sync.Mutex
+	handler http.Handler
+	quiet   bool
+}
+
+// mock regenerates the spec like watch does and serves a mock of its
+// operations, so that clients can be written before the handlers exist.
+// The X-Mock-Status header or mock_status query parameter selects the
+// response status.
+func mock(opt options, diags *generator.Diagnostics) int {
+	addr := opt.Mock.Addr
+	if addr == "" {
+		addr = "localhost:8080"
+	}
+
+	srv := &mockServer{quiet: opt.Mock.Quiet}
+	go watchSources(opt, func(swag *spec.Swagger, d *generator.Diagnostics) {
+		if err := d.Render(os.Stderr, opt.Diagnostics); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+		if swag == nil {
+			return
+		}
+		srv.mu.Lock()
+		srv.handler = generator.Mock(swag)
+		srv.mu.Unlock()
+		fmt.Fprintf(os.Stderr, "%s mock updated: %d error(s), %d warning(s)\n", time.Now().Format("15:04:05"),
+			d.Count(generator.SeverityError), d.Count(generator.SeverityWarning))
+	})
+
+	fmt.Fprintf(os.Stderr, "serving mock on http://%s/\n", addr)
+	if err := http.ListenAndServe(addr, srv); err != nil {
+		diags.Errorf(token.Position{}, generator.CodeOutput, "%v", err)
+		return 1
+	}
+	return 0
+}
+
+func (self *mockServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	self.mu.Lock()
+	h := self.handler
+	self.mu.Unlock()
+	if h == nil {
+		http.Error(w, "the spec has not been generated yet", http.StatusServiceUnavailable)
+		return
+	}
+
+	rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+	h.ServeHTTP(rec, r)
+	if !self.quiet {
+		fmt.Fprintf(os.Stderr, "%s %s %s %d\n", time.Now().Format("15:04:05"), r.Method, r.URL.RequestURI(), rec.status)
+	}
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (self *statusRecorder) WriteHeader(status int) {
+	self.status = status
+	self.ResponseWriter.WriteHeader(status)
+}
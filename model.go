@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"go/token"
+	"io/ioutil"
+
+	"github.com/xucx/go2swag/generator"
+)
+
+type modelOptions struct {
+	Out string `goptions:"-o, description='model output file (default: stdout)'"`
+}
+
+// model writes the scan result as JSON instead of a spec, for tools that
+// audit routes without caring about swagger.
+func model(opt options, diags *generator.Diagnostics) int {
+	s, d, err := generator.Scan(context.Background(), config(".", opt))
+	diags.Append(d.List()...)
+	if err != nil {
+		diags.Errorf(token.Position{}, generator.CodeLoad, "%v", err)
+		return 1
+	}
+
+	b, err := json.MarshalIndent(s.Model(), "", "  ")
+	if err != nil {
+		diags.Errorf(token.Position{}, generator.CodeOutput, "%v", err)
+		return 1
+	}
+
+	if opt.Model.Out == "" {
+		fmt.Println(string(b))
+		return 0
+	}
+	if err := ioutil.WriteFile(opt.Model.Out, append(b, '\n'), 0644); err != nil {
+		diags.Errorf(token.Position{Filename: opt.Model.Out}, generator.CodeOutput, "%v", err)
+		return 1
+	}
+	return 0
+}
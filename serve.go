@@ -0,0 +1,166 @@
+package main
+
+import (
+	"fmt"
+	"go/token"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-openapi/spec"
+	"github.com/xucx/go2swag/generator"
+)
+
+type serveOptions struct {
+	Addr    string `goptions:"--addr, description='address to listen on (default: localhost:8090)'"`
+	Backend string `goptions:"--backend, description='base URL try-it-out requests are proxied to, e.g. http://localhost:8080'"`
+}
+
+// docServer serves the latest generated spec with the documentation page
+// and tells open pages to reload when the spec changes.
+type docServer struct {
+	mu      sync.Mutex
+	spec    []byte
+	clients map[chan struct{}]bool
+	backend *url.URL
+}
+
+// serve regenerates the spec like watch does and serves it, with a
+// documentation page that needs nothing but the go2swag binary.
+func serve(opt options, diags *generator.Diagnostics) int {
+	addr := opt.Serve.Addr
+	if addr == "" {
+		addr = "localhost:8090"
+	}
+
+	srv := &docServer{clients: map[chan struct{}]bool{}}
+	if opt.Serve.Backend != "" {
+		u, err := url.Parse(opt.Serve.Backend)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			diags.Errorf(token.Position{}, generator.CodeInput, "invalid --backend %q, want a URL like http://localhost:8080", opt.Serve.Backend)
+			return 1
+		}
+		srv.backend = u
+	}
+
+	go watchSources(opt, func(swag *spec.Swagger, d *generator.Diagnostics) {
+		if err := d.Render(os.Stderr, opt.Diagnostics); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+		if swag == nil {
+			return
+		}
+		b, err := generator.Marshal(swag, false, true)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return
+		}
+		srv.publish(b)
+		fmt.Fprintf(os.Stderr, "%s spec updated: %d error(s), %d warning(s)\n", time.Now().Format("15:04:05"),
+			d.Count(generator.SeverityError), d.Count(generator.SeverityWarning))
+	})
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", srv.index)
+	mux.HandleFunc("/swagger.json", srv.swagger)
+	mux.HandleFunc("/events", srv.events)
+	mux.HandleFunc("/proxy/", srv.proxy)
+
+	fmt.Fprintf(os.Stderr, "serving documentation on http://%s/\n", addr)
+	if err := http.ListenAndServe(addr, mux); err != nil {
+		diags.Errorf(token.Position{}, generator.CodeOutput, "%v", err)
+		return 1
+	}
+	return 0
+}
+
+func (self *docServer) publish(b []byte) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	self.spec = b
+	for c := range self.clients {
+		select {
+		case c <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (self *docServer) index(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprint(w, generator.DocPage(generator.DocPageConfig{Spec: "/swagger.json", Events: "/events", Proxy: "/proxy"}))
+}
+
+func (self *docServer) swagger(w http.ResponseWriter, r *http.Request) {
+	self.mu.Lock()
+	b := self.spec
+	self.mu.Unlock()
+	if b == nil {
+		http.Error(w, "the spec has not been generated yet", http.StatusServiceUnavailable)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Write(b)
+}
+
+// events is a server-sent event stream with one "reload" event per spec
+// update.
+func (self *docServer) events(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	c := make(chan struct{}, 1)
+	self.mu.Lock()
+	self.clients[c] = true
+	self.mu.Unlock()
+	defer func() {
+		self.mu.Lock()
+		delete(self.clients, c)
+		self.mu.Unlock()
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-store")
+	fmt.Fprint(w, ": connected\n\n")
+	flusher.Flush()
+
+	for {
+		select {
+		case <-c:
+			fmt.Fprint(w, "event: reload\ndata: {}\n\n")
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// proxy forwards try-it-out requests below /proxy to the backend, so the
+// page does not run into CORS.
+func (self *docServer) proxy(w http.ResponseWriter, r *http.Request) {
+	if self.backend == nil {
+		http.Error(w, "no backend configured, start serve with --backend", http.StatusBadGateway)
+		return
+	}
+	rp := httputil.NewSingleHostReverseProxy(self.backend)
+	director := rp.Director
+	rp.Director = func(req *http.Request) {
+		req.URL.Path = "/" + strings.TrimPrefix(req.URL.Path, "/proxy/")
+		req.URL.RawPath = ""
+		director(req)
+		req.Host = self.backend.Host
+	}
+	rp.ServeHTTP(w, r)
+}
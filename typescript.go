@@ -0,0 +1,38 @@
+package main
+
+import (
+	"fmt"
+	"go/token"
+	"io/ioutil"
+
+	"github.com/xucx/go2swag/generator"
+)
+
+type tsOptions struct {
+	Out string `goptions:"-o, description='TypeScript output file (default: stdout)'"`
+}
+
+// typescript writes TypeScript types and fetch wrappers for the spec, for
+// frontends calling the API.
+func typescript(opt options, diags *generator.Diagnostics) int {
+	swag, scan := generateScan(opt, diags)
+	if swag == nil {
+		return 1
+	}
+
+	b, err := generator.TypeScript(swag, scan)
+	if err != nil {
+		diags.Errorf(token.Position{}, generator.CodeOutput, "%v", err)
+		return 1
+	}
+
+	if opt.TS.Out == "" {
+		fmt.Print(string(b))
+		return 0
+	}
+	if err := ioutil.WriteFile(opt.TS.Out, b, 0644); err != nil {
+		diags.Errorf(token.Position{Filename: opt.TS.Out}, generator.CodeOutput, "%v", err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-openapi/spec"
+	"github.com/xucx/go2swag/generator"
+)
+
+// watch regenerates the spec whenever a Go file of the scanned packages,
+// an input or an overlay changes. It polls, so it works anywhere, and the
+// scan cache keeps rescans down to the packages that changed.
+func watch(opt options, diags *generator.Diagnostics) int {
+	watchSources(opt, func(swag *spec.Swagger, d *generator.Diagnostics) {
+		if swag != nil {
+			if err := generator.Save(swag, true, opt.Out); err != nil {
+				d.Errorf(token.Position{Filename: opt.Out}, generator.CodeOutput, "%v", err)
+			}
+		}
+		if err := d.Render(os.Stderr, opt.Diagnostics); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+		fmt.Fprintf(os.Stderr, "%s wrote %s: %d error(s), %d warning(s)\n", time.Now().Format("15:04:05"), opt.Out,
+			d.Count(generator.SeverityError), d.Count(generator.SeverityWarning))
+	})
+	return 0
+}
+
+// watchSources generates the spec, hands it to update and does so again
+// after every change to the sources, until the process is stopped.
+func watchSources(opt options, update func(*spec.Swagger, *generator.Diagnostics)) {
+	interval, debounce := opt.Interval, opt.Debounce
+	if interval <= 0 {
+		interval = 500 * time.Millisecond
+	}
+	if debounce <= 0 {
+		debounce = 300 * time.Millisecond
+	}
+
+	files := []string{}
+	for {
+		d := new(generator.Diagnostics)
+		cfg := config(".", opt)
+		cfg.PostProcessors = append(cfg.PostProcessors, func(_ *spec.Swagger, s *generator.Scanner) error {
+			files = s.Files()
+			return nil
+		})
+		update(generateWith(cfg, d), d)
+
+		watched := append(append([]string{"go.mod", "go.sum"}, opt.In...), opt.Overlays...)
+		watched = append(watched, files...)
+
+		prev := snapshot(watched)
+		for {
+			time.Sleep(interval)
+			next := snapshot(watched)
+			if next == prev {
+				continue
+			}
+			// wait for the editor, or git checkout, to finish writing
+			for {
+				time.Sleep(debounce)
+				settled := snapshot(watched)
+				if settled == next {
+					break
+				}
+				next = settled
+			}
+			break
+		}
+	}
+}
+
+// snapshot fingerprints the files and every Go file next to them, so that
+// added and removed files count as changes too. The current directory is
+// always included, so a failed first load still has something to watch.
+func snapshot(files []string) string {
+	names := map[string]bool{}
+	dirs := map[string]bool{".": true}
+	for _, f := range files {
+		names[f] = true
+		if strings.HasSuffix(f, ".go") {
+			dirs[filepath.Dir(f)] = true
+		}
+	}
+	for dir := range dirs {
+		infos, err := ioutil.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, fi := range infos {
+			if strings.HasSuffix(fi.Name(), ".go") && !strings.HasSuffix(fi.Name(), "_test.go") {
+				names[filepath.Join(dir, fi.Name())] = true
+			}
+		}
+	}
+
+	list := []string{}
+	for name := range names {
+		list = append(list, name)
+	}
+	sort.Strings(list)
+
+	var b strings.Builder
+	for _, name := range list {
+		if fi, err := os.Stat(name); err == nil {
+			fmt.Fprintf(&b, "%s %d %d\n", name, fi.Size(), fi.ModTime().UnixNano())
+		} else {
+			fmt.Fprintf(&b, "%s missing\n", name)
+		}
+	}
+	return b.String()
+}